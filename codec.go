@@ -0,0 +1,93 @@
+package dna
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const minimerBytes = int(kmerwords * 8)
+
+// Encoder writes Minimers to a stream as little endian words
+type Encoder struct {
+	w   io.Writer
+	buf [kmerwords * 8]byte
+}
+
+// NewEncoder returns an Encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes a single Minimer
+func (e *Encoder) Encode(kmer Minimer) error {
+	for i := range kmer {
+		binary.LittleEndian.PutUint64(e.buf[i*8:(i+1)*8], kmer[i])
+	}
+	_, err := e.w.Write(e.buf[:])
+	return err
+}
+
+// EncodeKmer writes the Minimer form of a Kmer
+func (e *Encoder) EncodeKmer(kmer Kmer) error {
+	return e.Encode(kmer.ToMini())
+}
+
+// Decoder reads Minimers written by an Encoder
+type Decoder struct {
+	r   io.Reader
+	buf []byte
+}
+
+// NewDecoder returns a Decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next Minimer. It returns io.EOF when the stream ends on a
+// record boundary and io.ErrUnexpectedEOF when it ends inside a record.
+func (d *Decoder) Decode() (Minimer, error) {
+	var list [1]Minimer
+	_, err := d.DecodeInto(list[:])
+	return list[0], err
+}
+
+// DecodeKmer reads the next Minimer as a Kmer
+func (d *Decoder) DecodeKmer() (Kmer, error) {
+	kmer, err := d.Decode()
+	if err != nil {
+		return Kmer{}, err
+	}
+	return kmer.ToKmer(), nil
+}
+
+// DecodeInto fills list with as many Minimers as are available and returns
+// the number read. Once the stream is exhausted it returns 0 and io.EOF; a
+// stream that ends inside a record yields the complete records before it and
+// io.ErrUnexpectedEOF.
+func (d *Decoder) DecodeInto(list Mmerlist) (int, error) {
+	if cap(d.buf) < len(list)*minimerBytes {
+		d.buf = make([]byte, len(list)*minimerBytes)
+	}
+	d.buf = d.buf[:len(list)*minimerBytes]
+	read, err := io.ReadFull(d.r, d.buf)
+	n := read / minimerBytes
+	for i := 0; i < n; i++ {
+		list[i] = d.minimer(i)
+	}
+	switch {
+	case err == io.ErrUnexpectedEOF && read%minimerBytes == 0:
+		err = nil
+	case err == io.EOF:
+		n = 0
+	}
+	return n, err
+}
+
+func (d *Decoder) minimer(i int) Minimer {
+	var kmer Minimer
+	b := d.buf[i*minimerBytes : (i+1)*minimerBytes]
+	for j := range kmer {
+		kmer[j] = binary.LittleEndian.Uint64(b[j*8 : (j+1)*8])
+	}
+	return kmer
+}
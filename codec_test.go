@@ -0,0 +1,113 @@
+package dna
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func encodeAll(t *testing.T, n int) ([]Minimer, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	var mmers []Minimer
+	for i := 0; i < n; i++ {
+		kmer, _ := ParseKmer("ACGTACGTTGCA"[:i%12+1])
+		mmers = append(mmers, kmer.ToMini())
+		if err := enc.Encode(mmers[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mmers, buf.Bytes()
+}
+
+func TestDecodeCleanEnd(t *testing.T) {
+	mmers, data := encodeAll(t, 3)
+	dec := NewDecoder(bytes.NewReader(data))
+	for i, want := range mmers {
+		got, err := dec.Decode()
+		if err != nil || got != want {
+			t.Fatalf("record %d: got %v, %v", i, got, err)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("got %v at the end, want io.EOF", err)
+	}
+	if _, err := NewDecoder(bytes.NewReader(nil)).Decode(); err != io.EOF {
+		t.Errorf("got %v on an empty stream, want io.EOF", err)
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	_, data := encodeAll(t, 3)
+	data = data[:len(data)-3]
+	dec := NewDecoder(bytes.NewReader(data))
+	for i := 0; i < 2; i++ {
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+	if _, err := dec.Decode(); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v for a truncated record, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecodeIntoBatches(t *testing.T) {
+	mmers, data := encodeAll(t, 10)
+	dec := NewDecoder(bytes.NewReader(data))
+	list := make(Mmerlist, 4)
+	var got []Minimer
+	for _, want := range []int{4, 4, 2} {
+		n, err := dec.DecodeInto(list)
+		if err != nil || n != want {
+			t.Fatalf("got %d, %v, want %d records", n, err, want)
+		}
+		got = append(got, list[:n]...)
+	}
+	if n, err := dec.DecodeInto(list); n != 0 || err != io.EOF {
+		t.Errorf("got %d, %v at the end, want 0, io.EOF", n, err)
+	}
+	for i := range mmers {
+		if got[i] != mmers[i] {
+			t.Fatalf("record %d: got %v, want %v", i, got[i], mmers[i])
+		}
+	}
+
+	// A truncated stream yields its complete records first
+	dec = NewDecoder(bytes.NewReader(data[:len(data)-1]))
+	if n, err := dec.DecodeInto(make(Mmerlist, 20)); n != 9 || err != io.ErrUnexpectedEOF {
+		t.Errorf("got %d, %v for a truncated stream, want 9, io.ErrUnexpectedEOF", n, err)
+	}
+}
+
+// failingWriter accepts limit bytes and then fails
+type failingWriter struct {
+	limit int
+}
+
+var errWrite = errors.New("disk full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errWrite
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestEncodeWriteError(t *testing.T) {
+	enc := NewEncoder(&failingWriter{limit: minimerBytes + 1})
+	kmer, _ := ParseKmer("ACGT")
+	if err := enc.EncodeKmer(kmer); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeKmer(kmer); err != errWrite {
+		t.Errorf("got %v, want the writer's error", err)
+	}
+	if err := kmer.Write(&failingWriter{}); err != errWrite {
+		t.Errorf("Kmer.Write got %v, want the writer's error", err)
+	}
+}
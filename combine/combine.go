@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+	"github.com/ericpauley/dna/setops"
+)
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+var aggregates = map[string]setops.Aggregate{
+	"sum": setops.Sum,
+	"max": setops.Max,
+	"min": setops.Min,
+}
+
+func main() {
+	var op, agg, foutput string
+	var abundances dna.CountList
+	flag.StringVar(&op, "op", "union", "The set operation: union, intersect or subtract (first minus the rest)")
+	flag.StringVar(&agg, "combine", "sum", "How union and intersect combine counts: sum, max or min")
+	flag.Var(&abundances, "min-abundance", "A comma separated list of per input min abundances, a single value applies to all")
+	flag.StringVar(&foutput, "out", "", "The output filename (default <op>.merged{L}.h5)")
+	flag.Parse()
+	names := flag.Args()
+	if len(names) < 2 {
+		fmt.Println("Error: Must define at least two input files!")
+		return
+	}
+	aggregate, ok := aggregates[agg]
+	if !ok {
+		fmt.Println("Error: Unknown -combine", agg)
+		return
+	}
+	if len(abundances) > 1 && len(abundances) != len(names) {
+		fmt.Println("Error: -min-abundance needs one value or one per input")
+		return
+	}
+	var tables []*counttable.CountTable
+	var ops []setops.Operand
+	for i, name := range names {
+		table, err := counttable.Open(name)
+		check(err)
+		defer table.Close()
+		if len(tables) > 0 && table.Length() != tables[0].Length() {
+			fmt.Println("Error: Inputs hold kmers of different lengths")
+			return
+		}
+		tables = append(tables, table)
+		min := uint32(1)
+		if len(abundances) == 1 {
+			min = uint32(abundances[0])
+		} else if len(abundances) > 1 {
+			min = uint32(abundances[i])
+		}
+		ops = append(ops, setops.Operand{Stream: table.Stream(), MinAbundance: min})
+	}
+	var result chan []dna.Kmer
+	switch op {
+	case "union":
+		result = setops.Union(ops, aggregate)
+	case "intersect":
+		result = setops.Intersect(ops, aggregate)
+	case "subtract":
+		result = setops.Subtract(ops[0], ops[1:]...)
+	default:
+		fmt.Println("Error: Unknown -op", op)
+		return
+	}
+	if foutput == "" {
+		foutput = op + ".merged" + strconv.Itoa(tables[0].Length()) + ".h5"
+	}
+	out, err := counttable.Create(foutput)
+	check(err)
+	written := 0
+	for kmers := range result {
+		for _, kmer := range kmers {
+			check(out.Write(kmer))
+		}
+		written += len(kmers)
+	}
+	for _, table := range tables {
+		check(table.Err())
+	}
+	check(out.Close())
+	fmt.Println("Wrote", written, "kmers to", foutput)
+}
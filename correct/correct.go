@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+)
+
+var minAbundance uint = 3
+var maxCorrections = 4
+var canonical bool
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+// kmerAt encodes the k base pairs at offset i of seq
+func kmerAt(seq []byte, i, k int) (dna.Kmer, bool) {
+	var kmer dna.Kmer
+	for j := i + k - 1; j >= i; j-- {
+		bp, ok := dna.EncodeBase(seq[j])
+		if !ok {
+			return kmer, false
+		}
+		kmer.Push(bp)
+	}
+	if canonical {
+		kmer = kmer.Canonical()
+	}
+	return kmer, true
+}
+
+type corrector struct {
+	table *counttable.CountTable
+	k     int
+	cache map[dna.Minimer]bool
+}
+
+// solid reports whether the kmer at offset i reaches minAbundance
+func (c *corrector) solid(seq []byte, i int) bool {
+	kmer, ok := kmerAt(seq, i, c.k)
+	if !ok {
+		return false
+	}
+	mmer := kmer.ToMini()
+	if s, ok := c.cache[mmer]; ok {
+		return s
+	}
+	count, err := c.table.Count(kmer)
+	check(err)
+	if len(c.cache) > 1<<22 {
+		c.cache = make(map[dna.Minimer]bool)
+	}
+	c.cache[mmer] = count >= uint32(minAbundance)
+	return c.cache[mmer]
+}
+
+// covering counts the solid kmers covering position p
+func (c *corrector) covering(seq []byte, p int) (solid, total int) {
+	first := p - c.k + 1
+	if first < 0 {
+		first = 0
+	}
+	for i := first; i <= p && i+c.k <= len(seq); i++ {
+		total++
+		if c.solid(seq, i) {
+			solid++
+		}
+	}
+	return
+}
+
+type correction struct {
+	pos      int
+	from, to byte
+}
+
+// correct fixes single base errors in place. The first weak kmer following
+// a solid one points at its last base, a read starting with weak kmers at the
+// base just before the first solid one. A substitution is kept only if it
+// makes every kmer covering the base solid.
+func (c *corrector) correct(seq []byte) ([]correction, bool) {
+	var fixes []correction
+	for len(fixes) < maxCorrections {
+		weak, firstSolid := -1, -1
+		for i := 0; i+c.k <= len(seq); i++ {
+			s := c.solid(seq, i)
+			if s && firstSolid < 0 {
+				firstSolid = i
+			}
+			if !s && weak < 0 {
+				weak = i
+				if i > 0 {
+					break
+				}
+			}
+			if weak == 0 && firstSolid >= 0 {
+				break
+			}
+		}
+		if weak < 0 {
+			return fixes, true
+		}
+		var p int
+		switch {
+		case weak > 0:
+			p = weak + c.k - 1
+		case firstSolid > 0:
+			p = firstSolid - 1
+		default:
+			return fixes, false
+		}
+		orig := seq[p]
+		best, bestSolid := byte(0), 0
+		for _, b := range []byte("ACGT") {
+			if b == orig {
+				continue
+			}
+			seq[p] = b
+			if solid, total := c.covering(seq, p); solid == total && solid > bestSolid {
+				best, bestSolid = b, solid
+			}
+		}
+		if best == 0 {
+			seq[p] = orig
+			return fixes, false
+		}
+		seq[p] = best
+		fixes = append(fixes, correction{p, orig, best})
+	}
+	return fixes, false
+}
+
+func main() {
+	var finput, foutput, flog string
+	flag.StringVar(&finput, "in", "", "The FASTA/FASTQ reads to correct")
+	flag.StringVar(&foutput, "out", "", "The corrected reads (default stdout)")
+	flag.StringVar(&flog, "log", "", "The per read correction log")
+	flag.UintVar(&minAbundance, "min-abundance", 3, "Min number of occurences to be solid")
+	flag.IntVar(&maxCorrections, "max-corrections", 4, "Max substitutions per read")
+	flag.BoolVar(&canonical, "canonical", false, "The table holds canonical kmers")
+	flag.Parse()
+	if finput == "" || flag.NArg() != 1 {
+		fmt.Println("Error: Must define an -in file and one merged table!")
+		return
+	}
+	table, err := counttable.Open(flag.Arg(0))
+	check(err)
+	defer table.Close()
+	c := &corrector{table, table.Length(), make(map[dna.Minimer]bool)}
+
+	f, err := os.Open(finput)
+	check(err)
+	defer f.Close()
+	in, err := dna.Decompress(f)
+	check(err)
+	defer in.Close()
+	outf := os.Stdout
+	if foutput != "" {
+		outf, err = os.Create(foutput)
+		check(err)
+		defer outf.Close()
+	}
+	out := bufio.NewWriter(outf)
+	defer out.Flush()
+	var log *bufio.Writer
+	if flog != "" {
+		f, err := os.Create(flog)
+		check(err)
+		defer f.Close()
+		log = bufio.NewWriter(f)
+		defer log.Flush()
+		fmt.Fprintln(log, "name\tstatus\tcorrections")
+	}
+	var reads, corrected, failed int
+	check(dna.ReadRecords(bufio.NewReader(in), func(name, seq, qual []byte) bool {
+		reads++
+		fixes, ok := c.correct(seq)
+		if len(fixes) > 0 {
+			corrected++
+		}
+		if !ok {
+			failed++
+		}
+		if qual != nil {
+			fmt.Fprintf(out, "@%s\n%s\n+\n%s\n", name, seq, qual)
+		} else {
+			fmt.Fprintf(out, ">%s\n%s\n", name, seq)
+		}
+		if log != nil {
+			status := "ok"
+			if !ok {
+				status = "uncorrectable"
+			}
+			fmt.Fprintf(log, "%s\t%s\t", name, status)
+			for i, fix := range fixes {
+				if i > 0 {
+					log.WriteByte(',')
+				}
+				fmt.Fprintf(log, "%d:%c>%c", fix.pos, fix.from, fix.to)
+			}
+			log.WriteByte('\n')
+		}
+		return true
+	}))
+	fmt.Fprintln(os.Stderr, "Reads", reads, "corrected", corrected, "uncorrectable", failed)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"runtime"
@@ -12,6 +13,8 @@ import (
 	"sync"
 	"time"
 	"unsafe"
+
+	"github.com/ericpauley/dna"
 )
 
 func check(e error) {
@@ -26,24 +29,35 @@ var maxmem uint = 2048 * 1024 * 1024
 var maxdisk uint = 10 * 1024 * 1024 * 1024
 var maxCores uint
 var minAbundance = 3
-var counts countList
+var counts dna.CountList
 var minsize = 8
 var maxsize = 30
 
+// eachLength calls tocall with every length of a scanned kmer from minsize
+// up, the lengths counted
+func eachLength(kmer dna.Kmer, tocall func(dna.Kmer)) {
+	for l := kmer.Length; l >= uint32(minsize); l-- {
+		kmer.Truncate(l)
+		tocall(kmer)
+	}
+}
+
 func calcSectors(f *os.File) []sector {
 	kmers := make([]int, 0, 8*1024*1024)
 	println("Calculating sectors")
 	checked := 0
-	scanned := scan(f, func(kmer kmer) bool {
+	scanned := scan(f, func(kmer dna.Kmer) bool {
 		checked++
 		if checked > 8*1024*1024 {
 			return false
 		}
-		kmers = append(kmers, int(kmer.getPrefix()))
+		eachLength(kmer, func(kmer dna.Kmer) {
+			kmers = append(kmers, int(kmer.GetPrefix()))
+		})
 		return true
 	}, minsize, maxsize, false)
 	sort.Ints(kmers)
-	maxsize := uint(float64(maxmem/uint(unsafe.Sizeof(kmer{}))) * scanned)
+	maxsize := uint(float64(maxmem/uint(unsafe.Sizeof(dna.Minimer{}))) * scanned)
 	var sectors []sector
 	var size uint
 	var lastKmer int
@@ -58,39 +72,62 @@ func calcSectors(f *os.File) []sector {
 			size = 0
 		}
 	}
-	sectors = append(sectors, sector{start: uint(lastKmer), end: uint(maxKmer.getPrefix() + 1)})
+	sectors = append(sectors, sector{start: uint(lastKmer), end: prefixes})
 	return sectors
 }
 
-func saveChunks(oname string, counts countList, sectors *[]sector, start time.Time, sorted chan chan kmerlist, sjoin *sync.WaitGroup) {
+// kmercount is a Minimer and the number of times it was seen
+type kmercount struct {
+	kmer  dna.Minimer
+	count uint32
+}
+
+func saveChunks(oname string, counts dna.CountList, sectors *[]sector, start time.Time, sorted chan chan dna.Mmerlist, sjoin *sync.WaitGroup) {
 	outfile, err := os.Create(oname)
 	check(err)
+	w := bufio.NewWriterSize(outfile, 4*1024*1024)
+	header := dna.NewHeader(dna.LayoutMinimerCount)
+	header.CountWidth = 4
+	header.MinLength = uint32(minsize)
+	header.MaxLength = uint32(maxsize)
+	check(header.Write(w))
 	writechan := make(chan kmercount, 100)
 	var writerJoin sync.WaitGroup
 	writerJoin.Add(1)
 	go func() {
+		enc := dna.NewEncoder(w)
+		b := make([]byte, 4)
 		for kc := range writechan {
-			kc.kmer.write(outfile)
-			b := make([]byte, 4)
+			check(enc.Encode(kc.kmer))
 			binary.LittleEndian.PutUint32(b, kc.count)
-			outfile.Write(b)
+			_, err := w.Write(b)
+			check(err)
 		}
+		check(w.Flush())
+		check(outfile.Close())
 		writerJoin.Done()
 	}()
 	println("Ready to save sectors")
 	for towrite := range sorted {
+		if towrite == nil {
+			continue
+		}
 		kmers := <-towrite
 		println("Received sector", len(kmers))
 		var current kmercount
 		for _, kmer := range kmers {
-			if current.kmer == kmer {
+			if current.count > 0 && current.kmer == kmer {
 				current.count++
 			} else {
-				writechan <- current
-				current.kmer = kmer
-				current.count = 1
+				if current.count > 0 {
+					writechan <- current
+				}
+				current = kmercount{kmer, 1}
 			}
 		}
+		if current.count > 0 {
+			writechan <- current
+		}
 	}
 	close(writechan)
 	writerJoin.Wait()
@@ -102,20 +139,20 @@ func writeChunk(s *sector, pjoin *sync.WaitGroup) {
 	check(err)
 	s.tmpfile = t
 	bufd := bufio.NewWriterSize(t, 4*1024*1024)
-	check(err)
+	enc := dna.NewEncoder(bufd)
 	for kmer := range s.c {
 		s.len++
-		kmer.write(bufd)
+		check(enc.Encode(kmer))
 	}
-	bufd.Flush()
+	check(bufd.Flush())
 	pjoin.Done()
 }
 
-func processChunks(c chan kmerlist, sorted chan chan kmerlist, ojoin *sync.WaitGroup) {
+func processChunks(c chan dna.Mmerlist, sorted chan chan dna.Mmerlist, ojoin *sync.WaitGroup) {
 	println("Process started")
 	for kmers := range c {
 		println("Sorting chunk!")
-		output := make(chan kmerlist)
+		output := make(chan dna.Mmerlist)
 		sorted <- output
 		sort.Sort(kmers)
 		output <- kmers
@@ -125,25 +162,29 @@ func processChunks(c chan kmerlist, sorted chan chan kmerlist, ojoin *sync.WaitG
 
 func revcmp() {
 	f, err := os.Open("ecoli.count.31")
+	check(err)
 	reader := bufio.NewReaderSize(f, 4*1024*1024)
+	header, err := dna.ReadHeader(reader)
 	check(err)
-	b := make([]byte, 2)
-	var counts kmercountlist
-	for true {
-		k := readKmer(reader).minimize(31)
-		l, err := reader.Read(b)
-		if err != nil {
+	check(header.Check(dna.LayoutMinimerCount))
+	dec := dna.NewDecoder(reader)
+	b := make([]byte, 4)
+	var counts dna.Kmerlist
+	for {
+		mmer, err := dec.Decode()
+		if err == io.EOF {
 			break
 		}
-		if l < 2 {
-			break
-		}
-		count := binary.LittleEndian.Uint32(b)
-		counts = append(counts, kmercount{k, count})
+		check(err)
+		_, err = io.ReadFull(reader, b)
+		check(err)
+		k := mmer.ToKmer().Canonical()
+		k.Count = binary.LittleEndian.Uint32(b)
+		counts = append(counts, k)
 	}
 	sort.Sort(counts)
 	for _, count := range counts {
-		fmt.Println(count.kmer, count.count)
+		fmt.Println(count, count.Count)
 	}
 }
 
@@ -177,8 +218,8 @@ func main() {
 		maxDiskSectors = 30
 	}
 	passes := uint(len(sectors)-1)/maxDiskSectors + 1
-	toSort := make(chan kmerlist)
-	sorted := make(chan chan kmerlist, 100)
+	toSort := make(chan dna.Mmerlist)
+	sorted := make(chan chan dna.Mmerlist, 100)
 	var ojoin sync.WaitGroup
 	var sjoin sync.WaitGroup
 	for i := 0; uint(i) < maxCores; i++ {
@@ -197,22 +238,20 @@ func main() {
 		toProcess := sectors[min:max]
 		var pjoin sync.WaitGroup
 		pjoin.Add(len(toProcess))
-		var sectorMap [1 << 12]sector
+		var sectorMap [prefixes]*sector
 		for i := range toProcess {
-			toProcess[i].c = make(chan kmer, 100)
+			toProcess[i].c = make(chan dna.Minimer, 100)
 			go writeChunk(&toProcess[i], &pjoin)
 			for j := toProcess[i].start; j < toProcess[i].end; j++ {
-				sectorMap[j] = toProcess[i]
+				sectorMap[j] = &toProcess[i]
 			}
 		}
-		lowest := maxKmer
-		scan(f, func(kmer kmer) bool {
-			if kmer.cmp(lowest) == -1 {
-				lowest = kmer
-			}
-			if sectorMap[kmer.getPrefix()].c != nil {
-				sectorMap[kmer.getPrefix()].c <- kmer
-			}
+		scan(f, func(kmer dna.Kmer) bool {
+			eachLength(kmer, func(kmer dna.Kmer) {
+				if s := sectorMap[kmer.GetPrefix()]; s != nil {
+					s.c <- kmer.ToMini()
+				}
+			})
 			return true
 		}, minsize, maxsize, true)
 		for _, s := range toProcess {
@@ -221,15 +260,19 @@ func main() {
 		pjoin.Wait()
 		for i := range toProcess {
 			println("Reading sector")
-			data := make(kmerlist, sectors[i].len)
-			sectors[i].tmpfile.Seek(0, 0)
-			reader := bufio.NewReaderSize(sectors[i].tmpfile, 4096*1024*1024)
-			for j := 0; j < sectors[i].len; j++ {
-				data[j] = readKmer(reader)
-			}
-			sectors[i].tmpfile.Close()
-			err = os.Remove(sectors[i].tmpfile.Name())
+			s := &toProcess[i]
+			data := make(dna.Mmerlist, s.len)
+			_, err := s.tmpfile.Seek(0, 0)
 			check(err)
+			n, err := dna.NewDecoder(bufio.NewReaderSize(s.tmpfile, 4*1024*1024)).DecodeInto(data)
+			if err == nil && n != s.len {
+				err = fmt.Errorf("sector file %s holds %d of %d kmers", s.tmpfile.Name(), n, s.len)
+			}
+			if err != nil && !(err == io.EOF && s.len == 0) {
+				check(err)
+			}
+			s.tmpfile.Close()
+			check(os.Remove(s.tmpfile.Name()))
 			toSort <- data
 		}
 	}
@@ -2,41 +2,32 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"fmt"
 	"os"
 	"sync"
 	"time"
+
+	"github.com/ericpauley/dna"
 )
 
-func parser(ch chan []byte, join *sync.WaitGroup, tocall kmerhandler, running *bool, min int, max int) {
+func parser(ch chan []byte, join *sync.WaitGroup, tocall dna.Kmerhandler, running *bool, min int, max int) {
 	defer join.Done()
+	opts := dna.ScanOptions{Mode: dna.AllPositions, Min: min, Max: max}
 	for s := range ch {
-		var kmer kmer
-		kmer.init()
-		var pushed int
-		for i := len(s) - 1; i >= 0; i-- {
-			bp := uint64(s[i] >> 1 & 3)
-			kmer.push(bp)
-			pushed++
-			if min <= pushed {
-				if pushed >= max {
-					break
-				} else if !tocall(kmer) {
-					*running = false
-				}
-			}
+		if !opts.Scan(s, tocall) {
+			*running = false
 		}
 	}
 }
 
-func scan(f *os.File, tocall kmerhandler, min int, max int, verbose bool) float64 {
-	f.Seek(0, 0)
+func scan(f *os.File, tocall dna.Kmerhandler, min int, max int, verbose bool) float64 {
+	_, err := f.Seek(0, 0)
+	check(err)
 	start := time.Now()
 	fi, err := f.Stat()
 	check(err)
 	size := fi.Size()
-	scanner := bufio.NewScanner(bufio.NewReader(f))
+	counter := &dna.CountingReader{R: f}
 	running := true
 
 	c := make(chan []byte)
@@ -46,33 +37,19 @@ func scan(f *os.File, tocall kmerhandler, min int, max int, verbose bool) float6
 		go parser(c, &pjoin, tocall, &running, min, max)
 	}
 	index := 0
-	var current []byte
-	line := 0
-	for scanner.Scan() {
-		line++
-		b := scanner.Bytes()
-		if bytes.IndexByte(b, '>') != -1 {
-			index++
-			if index%10000 == 0 {
-				pos, err := f.Seek(0, 1)
-				check(err)
-				if verbose {
-					fmt.Print("\r", scanner.Text(), len(current), pos*100/size, time.Since(start), time.Since(start).Nanoseconds()/1000000*size/pos, "          ")
-				}
-			}
-			c <- current
-			current = make([]byte, 0)
-		} else {
-			current = append(current, b...)
-		}
-		if !running {
-			break
+	check(dna.ReadFasta(bufio.NewReader(counter), func(name, seq []byte) bool {
+		index++
+		if index%10000 == 0 && verbose && size > 0 {
+			pos := counter.N
+			fmt.Print("\r", string(name), len(seq), pos*100/size, time.Since(start), time.Since(start).Nanoseconds()/1000000*size/pos, "          ")
 		}
-	}
-	c <- current
+		c <- seq
+		return running
+	}))
 	close(c)
 	pjoin.Wait()
-	pos, err := f.Seek(0, 1)
-	check(err)
-	return float64(pos) / float64(size)
+	if size == 0 {
+		return 1
+	}
+	return float64(counter.N) / float64(size)
 }
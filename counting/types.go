@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+
+	"github.com/ericpauley/dna"
+)
+
+// prefixes is the number of kmer prefixes sectors are split on, see
+// dna.Kmer.GetPrefix
+const prefixes = 1 << 12
+
+// sector holds the kmers whose prefixes fall in [start, end), spooled to a
+// temporary file until it is sorted
+type sector struct {
+	start   uint
+	end     uint
+	tmpfile *os.File
+	c       chan dna.Minimer
+	len     int
+}
@@ -0,0 +1,38 @@
+package dna
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CountList is a flag.Value holding a comma separated list of counts, such
+// as per input min abundances
+type CountList []uint
+
+func (l *CountList) String() string {
+	return fmt.Sprint(*l)
+}
+
+// Set appends the counts of a comma separated list
+func (l *CountList) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		count, err := strconv.ParseUint(s, 10, 0)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, uint(count))
+	}
+	return nil
+}
+
+// Max returns the largest count, 0 for an empty list
+func (l CountList) Max() uint {
+	var max uint
+	for _, c := range l {
+		if c > max {
+			max = c
+		}
+	}
+	return max
+}
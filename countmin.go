@@ -0,0 +1,58 @@
+package dna
+
+// CountMinSketch estimates kmer counts in a fixed amount of memory. An
+// estimate never falls below the true count; counters saturate at 65535.
+// Add does not lock, so concurrent Adds can lose counts.
+type CountMinSketch struct {
+	mask uint64
+	rows [][]uint16
+}
+
+// NewCountMinSketch creates a sketch of depth rows, each holding width
+// counters rounded down to a power of two
+func NewCountMinSketch(width, depth int) *CountMinSketch {
+	size := 1
+	for size*2 <= width {
+		size *= 2
+	}
+	rows := make([][]uint16, depth)
+	for i := range rows {
+		rows[i] = make([]uint16, size)
+	}
+	return &CountMinSketch{uint64(size - 1), rows}
+}
+
+// hashes derives the row hashes of a Minimer by double hashing
+func (s *CountMinSketch) hashes(kmer Minimer) (uint64, uint64) {
+	h := kmer.Hash()
+	return h, Hashed(h) | 1
+}
+
+// Add counts one occurrence of the Minimer, only raising the counters that
+// hold its current estimate (conservative update)
+func (s *CountMinSketch) Add(kmer Minimer) {
+	estimate := s.Estimate(kmer)
+	if estimate >= 1<<16-1 {
+		return
+	}
+	h1, h2 := s.hashes(kmer)
+	for i, row := range s.rows {
+		j := (h1 + uint64(i)*h2) & s.mask
+		if uint32(row[j]) == estimate {
+			row[j]++
+		}
+	}
+}
+
+// Estimate returns an upper bound on the number of times the Minimer was
+// added
+func (s *CountMinSketch) Estimate(kmer Minimer) uint32 {
+	h1, h2 := s.hashes(kmer)
+	min := uint16(1<<16 - 1)
+	for i, row := range s.rows {
+		if c := row[(h1+uint64(i)*h2)&s.mask]; c < min {
+			min = c
+		}
+	}
+	return uint32(min)
+}
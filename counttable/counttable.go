@@ -0,0 +1,238 @@
+// Package counttable gives random access to the sorted kmer counts written
+// by merging.
+package counttable
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/go-hdf5"
+)
+
+// fenceStride is the number of records between fence index entries
+const fenceStride = 4096
+
+// lock serializes calls into HDF5, which is not safe for concurrent use
+var lock sync.Mutex
+
+// Record is a single kmer count as stored by merging. Every kmer in a table
+// has the same length, so they are stored LSB aligned without a sentinel.
+type Record struct {
+	Mmer  dna.Minimer `mmer`
+	Count uint32      `count`
+}
+
+// Kmer converts the record into a Kmer of the given length
+func (r Record) Kmer(length int) dna.Kmer {
+	var kmer dna.Kmer
+	kmer.Kmer = r.Mmer
+	kmer.Normalize(uint32(length))
+	kmer.Count = r.Count
+	return kmer
+}
+
+// CountTable is a read-only merged kmer table. It keeps the first kmer of
+// every fenceStride records in memory so a lookup reads a single block.
+// That block is reused between lookups, so open a table per goroutine.
+type CountTable struct {
+	file    *hdf5.File
+	table   *hdf5.Table
+	length  int
+	records int
+	fences  []dna.Minimer
+	block   []Record
+	err     error
+}
+
+var mergedName = regexp.MustCompile(`merged(\d+)(\.r[12])?\.h5$`)
+
+// LengthFromName returns the kmer length of a merged{L}.h5 file name, or
+// of the merged{L}.r1.h5 and merged{L}.r2.h5 names of a single mate
+func LengthFromName(name string) (int, error) {
+	m := mergedName.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return 0, fmt.Errorf("counttable: cannot tell kmer length from %s", name)
+	}
+	return strconv.Atoi(m[1])
+}
+
+// Open opens a merged{L}.h5 table, taking the kmer length from its name
+func Open(name string) (*CountTable, error) {
+	length, err := LengthFromName(name)
+	if err != nil {
+		return nil, err
+	}
+	return OpenLength(name, length)
+}
+
+// OpenLength opens a merged table holding kmers of the given length
+func OpenLength(name string, length int) (*CountTable, error) {
+	if length < 1 || length > dna.MaxLength {
+		return nil, fmt.Errorf("counttable: invalid kmer length %d", length)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	file, err := hdf5.OpenFile(name, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	table, err := file.OpenTable("kmers")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	t := &CountTable{file: file, table: table, length: length}
+	if t.records, err = table.NumPackets(); err != nil {
+		t.close()
+		return nil, err
+	}
+	fence := make([]Record, 1)
+	for i := 0; i < t.records; i += fenceStride {
+		if err := table.ReadPackets(i, 1, &fence); err != nil {
+			t.close()
+			return nil, err
+		}
+		t.fences = append(t.fences, fence[0].Mmer)
+	}
+	return t, nil
+}
+
+// Close releases the underlying file
+func (t *CountTable) Close() error {
+	lock.Lock()
+	defer lock.Unlock()
+	return t.close()
+}
+
+func (t *CountTable) close() error {
+	t.table.Close()
+	return t.file.Close()
+}
+
+// Length returns the length of the kmers in the table
+func (t *CountTable) Length() int {
+	return t.length
+}
+
+// Len returns the number of kmers in the table
+func (t *CountTable) Len() int {
+	return t.records
+}
+
+// readBlock loads the records following fence i
+func (t *CountTable) readBlock(i int) ([]Record, error) {
+	start := i * fenceStride
+	n := t.records - start
+	if n > fenceStride {
+		n = fenceStride
+	}
+	if cap(t.block) < n {
+		t.block = make([]Record, n)
+	}
+	t.block = t.block[:n]
+	lock.Lock()
+	defer lock.Unlock()
+	if err := t.table.ReadPackets(start, n, &t.block); err != nil {
+		return nil, err
+	}
+	return t.block, nil
+}
+
+// fence returns the block that would hold key, or -1 if key sorts first
+func (t *CountTable) fence(key dna.Minimer) int {
+	return sort.Search(len(t.fences), func(i int) bool {
+		return t.fences[i].Cmp(key) > 0
+	}) - 1
+}
+
+// Count returns the count of the kmer, which is zero if it is not present
+func (t *CountTable) Count(kmer dna.Kmer) (uint32, error) {
+	if int(kmer.Length) != t.length {
+		return 0, fmt.Errorf("counttable: kmer of length %d looked up in table of length %d", kmer.Length, t.length)
+	}
+	key := kmer.ToRaw()
+	i := t.fence(key)
+	if i < 0 {
+		return 0, nil
+	}
+	block, err := t.readBlock(i)
+	if err != nil {
+		return 0, err
+	}
+	j := sort.Search(len(block), func(j int) bool {
+		return block[j].Mmer.Cmp(key) >= 0
+	})
+	if j < len(block) && block[j].Mmer == key {
+		return block[j].Count, nil
+	}
+	return 0, nil
+}
+
+// Prefix calls tocall, in order, with every kmer in the table starting with
+// prefix until tocall returns false. A zero length prefix visits the whole
+// table.
+func (t *CountTable) Prefix(prefix dna.Kmer, tocall dna.Kmerhandler) error {
+	if int(prefix.Length) > t.length {
+		return nil
+	}
+	low := prefix
+	low.Truncate(prefix.Length)
+	low.Length = uint32(t.length)
+	high := low
+	for i := prefix.Length; i < uint32(t.length); i++ {
+		high.Kmer[i/32] |= 3 << (62 - 2*(i%32))
+	}
+	lo, hi := low.ToRaw(), high.ToRaw()
+	i := t.fence(lo)
+	if i < 0 {
+		i = 0
+	}
+	for ; i < len(t.fences); i++ {
+		block, err := t.readBlock(i)
+		if err != nil {
+			return err
+		}
+		for _, r := range block {
+			if r.Mmer.Cmp(lo) < 0 {
+				continue
+			}
+			if r.Mmer.Cmp(hi) > 0 || !tocall(r.Kmer(t.length)) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Stream sends every kmer of the table in order, in batches, and closes the
+// channel at the end or on the first error, which Err then reports. The
+// table must not be used otherwise until the stream is drained.
+func (t *CountTable) Stream() chan []dna.Kmer {
+	c := make(chan []dna.Kmer, 1)
+	go func() {
+		for i := range t.fences {
+			block, err := t.readBlock(i)
+			if err != nil {
+				t.err = err
+				break
+			}
+			kmers := make([]dna.Kmer, len(block))
+			for j, r := range block {
+				kmers[j] = r.Kmer(t.length)
+			}
+			c <- kmers
+		}
+		close(c)
+	}()
+	return c
+}
+
+// Err returns the error that ended a Stream early, if any
+func (t *CountTable) Err() error {
+	return t.err
+}
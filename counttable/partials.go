@@ -0,0 +1,81 @@
+package counttable
+
+import (
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/go-hdf5"
+)
+
+// partialsBatch is the number of records read from a sector table at once
+const partialsBatch = 10000
+
+// OpenPartials opens the groups of a prefixcounting output holding the
+// kmers of a mate, see dna.PartialGroups. Errors leave naming the file to
+// the caller.
+func OpenPartials(h5 *hdf5.File, mate int, pool bool) ([]*hdf5.Group, error) {
+	names, err := dna.PartialGroups(mate, pool, func(name string) bool {
+		group, err := h5.OpenGroup(name)
+		if err == nil {
+			group.Close()
+		}
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*hdf5.Group, len(names))
+	for i, name := range names {
+		if groups[i], err = h5.OpenGroup(name); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// ReadPartials calls tocall with every kmer of the sector tables in a
+// prefixcounting partials group or a DSK solid group. Kmers stored without
+// a length, as DSK does, are given the length size.
+func ReadPartials(group *hdf5.Group, size int, tocall func(dna.Kmer)) error {
+	num, err := group.NumObjects()
+	if err != nil {
+		return err
+	}
+	for i := uint(0); i < num; i++ {
+		name, err := group.ObjectNameByIndex(i)
+		if err != nil {
+			return err
+		}
+		if err := readSector(group, name, size, tocall); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSector(group *hdf5.Group, name string, size int, tocall func(dna.Kmer)) error {
+	table, err := group.OpenTable(name)
+	if err != nil {
+		return err
+	}
+	defer table.Close()
+	records, err := table.NumPackets()
+	if err != nil {
+		return err
+	}
+	for j := 0; j < records; j += partialsBatch {
+		toFetch := partialsBatch
+		if j+toFetch > records {
+			toFetch = records - j
+		}
+		kmers := make([]dna.Kmer, toFetch)
+		if err := table.Next(&kmers); err != nil {
+			return err
+		}
+		for _, kmer := range kmers {
+			if kmer.Length == 0 {
+				kmer.Normalize(uint32(size))
+			}
+			tocall(kmer)
+		}
+	}
+	return nil
+}
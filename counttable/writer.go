@@ -0,0 +1,65 @@
+package counttable
+
+import (
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/go-hdf5"
+)
+
+const writeBatch = 10000
+
+// Writer creates a table in the format written by merging. Kmers must be
+// written in increasing order and all have the same length.
+type Writer struct {
+	file   *hdf5.File
+	table  *hdf5.Table
+	buffer []Record
+}
+
+// Create creates or truncates a merged table
+func Create(name string) (*Writer, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	file, err := hdf5.CreateFile(name, hdf5.F_ACC_TRUNC)
+	if err != nil {
+		return nil, err
+	}
+	table, err := file.CreateTableFrom("kmers", Record{}, 1<<20, -1)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Writer{file, table, make([]Record, 0, writeBatch)}, nil
+}
+
+// Write adds a kmer and its count to the table
+func (w *Writer) Write(kmer dna.Kmer) error {
+	w.buffer = append(w.buffer, Record{kmer.ToRaw(), kmer.Count})
+	if len(w.buffer) >= writeBatch {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *Writer) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	err := w.table.Append(&w.buffer)
+	w.buffer = w.buffer[:0]
+	return err
+}
+
+// Close flushes the remaining kmers and closes the file
+func (w *Writer) Close() error {
+	err := w.flush()
+	lock.Lock()
+	defer lock.Unlock()
+	w.table.Close()
+	w.file.Flush(hdf5.F_SCOPE_GLOBAL)
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
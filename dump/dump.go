@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+	"github.com/ericpauley/go-hdf5"
+)
+
+var minAbundance uint = 1
+var maxAbundance uint
+var minsize uint = 1
+var maxsize uint
+var format string
+var mate int
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+// dumper writes the kmers that pass the filters, tagged with their mate
+// when dumping both mates of paired reads
+type dumper struct {
+	w    *bufio.Writer
+	seen int
+	mate int
+}
+
+func (d *dumper) dump(kmer dna.Kmer) bool {
+	if kmer.Count < uint32(minAbundance) || (maxAbundance > 0 && kmer.Count > uint32(maxAbundance)) {
+		return true
+	}
+	if kmer.Length < uint32(minsize) || (maxsize > 0 && kmer.Length > uint32(maxsize)) {
+		return true
+	}
+	d.seen++
+	var err error
+	switch {
+	case format == "fasta" && d.mate > 0:
+		_, err = fmt.Fprintf(d.w, ">%d count=%d mate=%d\n%v\n", d.seen, kmer.Count, d.mate, kmer)
+	case format == "fasta":
+		_, err = fmt.Fprintf(d.w, ">%d count=%d\n%v\n", d.seen, kmer.Count, kmer)
+	case d.mate > 0:
+		_, err = fmt.Fprintf(d.w, "%v\t%d\t%d\n", kmer, kmer.Count, d.mate)
+	default:
+		_, err = fmt.Fprintf(d.w, "%v\t%d\n", kmer, kmer.Count)
+	}
+	check(err)
+	return true
+}
+
+// dumpGroup writes every table of a prefixcounting partials group or a DSK
+// solid group. DSK stores 31-mers without a length.
+func (d *dumper) dumpGroup(group *hdf5.Group, size int) {
+	check(counttable.ReadPartials(group, size, func(kmer dna.Kmer) {
+		d.dump(kmer)
+	}))
+}
+
+func main() {
+	var foutput string
+	flag.UintVar(&minAbundance, "min-abundance", 1, "Min count of kmers to dump")
+	flag.UintVar(&maxAbundance, "max-abundance", 0, "Max count of kmers to dump (0 for no limit)")
+	flag.UintVar(&minsize, "min-size", 1, "Min length of kmers to dump")
+	flag.UintVar(&maxsize, "max-size", 0, "Max length of kmers to dump (0 for no limit)")
+	flag.StringVar(&format, "format", "tsv", "The output format: tsv (kmer and count) or fasta (count in the header)")
+	flag.StringVar(&foutput, "out", "", "The output filename (default stdout)")
+	flag.IntVar(&mate, "mate", 0, "Dump only the kmers of read 1 or 2 of paired prefixcounting outputs (0 for both, tagged with their mate)")
+	flag.Parse()
+	names := flag.Args()
+	if len(names) == 0 {
+		fmt.Println("Error: Must define an input file!")
+		return
+	}
+	if mate < 0 || mate > 2 {
+		fmt.Println("Error: -mate must be 0, 1 or 2")
+		return
+	}
+	if format != "tsv" && format != "fasta" {
+		fmt.Println("Error: Unknown -format", format)
+		return
+	}
+	out := os.Stdout
+	if foutput != "" {
+		f, err := os.Create(foutput)
+		check(err)
+		defer f.Close()
+		out = f
+	}
+	d := &dumper{w: bufio.NewWriterSize(out, 1<<20)}
+	for _, name := range names {
+		if filepath.Ext(name) == ".kct" {
+			table, err := dna.OpenFlatTable(name)
+			check(err)
+			table.Prefix(dna.Kmer{}, d.dump)
+			check(table.Close())
+			continue
+		}
+		if _, err := counttable.LengthFromName(name); err == nil {
+			table, err := counttable.Open(name)
+			check(err)
+			check(table.Prefix(dna.Kmer{}, d.dump))
+			check(table.Close())
+			continue
+		}
+		h5, err := hdf5.OpenFile(name, hdf5.F_ACC_RDONLY)
+		check(err)
+		if group, err := h5.OpenGroup("dsk/solid"); err == nil {
+			d.dumpGroup(group, 31)
+			group.Close()
+		} else {
+			// Both mates are dumped together when no -mate is given, each
+			// kmer tagged with its own
+			groups, err := counttable.OpenPartials(h5, mate, true)
+			if err != nil {
+				check(fmt.Errorf("%s %v", name, err))
+			}
+			for i, group := range groups {
+				if mate == 0 && len(groups) == 2 {
+					d.mate = i + 1
+				}
+				d.dumpGroup(group, 0)
+				group.Close()
+			}
+			d.mate = 0
+		}
+		h5.Close()
+	}
+	check(d.w.Flush())
+}
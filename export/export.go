@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+	"github.com/ericpauley/dna/kmerdb"
+)
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+var extensions = map[string]string{
+	"jellyfish":      ".jf",
+	"jellyfish-text": ".txt",
+	"kmc":            "",
+}
+
+// load reads every kmer of a merged or flat table
+func load(name string) ([]dna.Kmer, int) {
+	var kmers []dna.Kmer
+	collect := func(kmer dna.Kmer) bool {
+		kmers = append(kmers, kmer)
+		return true
+	}
+	if filepath.Ext(name) == ".kct" {
+		table, err := dna.OpenFlatTable(name)
+		check(err)
+		defer table.Close()
+		table.Prefix(dna.Kmer{}, collect)
+		return kmers, table.Length()
+	}
+	table, err := counttable.Open(name)
+	check(err)
+	defer table.Close()
+	check(table.Prefix(dna.Kmer{}, collect))
+	return kmers, table.Length()
+}
+
+func main() {
+	var format, foutput string
+	var canonical bool
+	flag.StringVar(&format, "format", "jellyfish", "The output format: jellyfish, jellyfish-text or kmc")
+	flag.StringVar(&foutput, "out", "", "The output filename, or prefix for kmc (default the input name with the format's extension)")
+	flag.BoolVar(&canonical, "canonical", false, "Write canonical kmers, as jellyfish -C and KMC do by default, and mark the output as canonical")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Println("Error: Must define an input file!")
+		return
+	}
+	ext, ok := extensions[format]
+	if !ok {
+		fmt.Println("Error: Unknown -format", format)
+		return
+	}
+	name := flag.Arg(0)
+	if foutput == "" {
+		foutput = strings.TrimSuffix(name, filepath.Ext(name)) + ext
+	}
+	kmers, length := load(name)
+	if canonical {
+		kmers = kmerdb.Canonicalize(kmers)
+	}
+	if format == "kmc" {
+		check(kmerdb.WriteKMC(foutput, length, canonical, kmers))
+		return
+	}
+	f, err := os.Create(foutput)
+	check(err)
+	defer f.Close()
+	if format == "jellyfish" {
+		check(kmerdb.WriteJellyfish(f, length, canonical, kmers))
+	} else {
+		check(kmerdb.WriteJellyfishText(f, kmers))
+	}
+}
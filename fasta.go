@@ -0,0 +1,43 @@
+package dna
+
+import (
+	"bufio"
+	"io"
+)
+
+// maxLine bounds the length of a single FASTA line
+const maxLine = 1 << 30
+
+// ReadFasta calls tocall with the name and sequence of every record in r,
+// joining multi-line sequences. Sequence before the first header is passed
+// with a nil name. Both slices are freshly allocated and may be retained.
+// Reading stops early when tocall returns false.
+func ReadFasta(r io.Reader, tocall func(name, seq []byte) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	var name []byte
+	var current []byte
+	started := false
+	for scanner.Scan() {
+		b := scanner.Bytes()
+		if len(b) > 0 && b[0] == '>' {
+			if started || len(current) > 0 {
+				if !tocall(name, current) {
+					return nil
+				}
+			}
+			name = append([]byte(nil), b[1:]...)
+			current = make([]byte, 0, len(current))
+			started = true
+		} else {
+			current = append(current, b...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if started || len(current) > 0 {
+		tocall(name, current)
+	}
+	return nil
+}
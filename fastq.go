@@ -0,0 +1,73 @@
+package dna
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ReadFastq calls tocall with the name, sequence and quality string of every
+// four line record in r. Quality strings may start with '@' since records
+// are split by line count, not by content. The slices are freshly allocated
+// and may be retained. Reading stops early when tocall returns false.
+func ReadFastq(r io.Reader, tocall func(name, seq, qual []byte) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	var lines [4][]byte
+	record := 0
+	for {
+		for i := 0; i < len(lines); i++ {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return err
+				}
+				if i == 0 {
+					return nil
+				}
+				return fmt.Errorf("dna: FASTQ record %d is truncated", record+1)
+			}
+			lines[i] = scanner.Bytes()
+			if i == 0 && len(lines[0]) == 0 {
+				// Tolerate blank lines between records
+				i--
+				continue
+			}
+			lines[i] = append([]byte(nil), lines[i]...)
+		}
+		record++
+		if lines[0][0] != '@' || len(lines[2]) == 0 || lines[2][0] != '+' {
+			return fmt.Errorf("dna: FASTQ record %d is malformed", record)
+		}
+		if len(lines[1]) != len(lines[3]) {
+			return fmt.Errorf("dna: FASTQ record %d has %d bases but %d qualities", record, len(lines[1]), len(lines[3]))
+		}
+		if !tocall(lines[0][1:], lines[1], lines[3]) {
+			return nil
+		}
+	}
+}
+
+// ReadRecords reads FASTA or FASTQ, told apart by the first non-blank byte,
+// and calls tocall for every record. FASTA records have a nil quality.
+func ReadRecords(r io.Reader, tocall func(name, seq, qual []byte) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !bytes.ContainsAny(b, "\r\n") {
+			break
+		}
+		br.ReadByte()
+	}
+	if b, _ := br.Peek(1); b[0] == '@' {
+		return ReadFastq(br, tocall)
+	}
+	return ReadFasta(br, func(name, seq []byte) bool {
+		return tocall(name, seq, nil)
+	})
+}
@@ -3,25 +3,28 @@ package main
 import (
 	"bufio"
 	"os"
+
+	"github.com/ericpauley/dna"
 )
 
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
 func main() {
-	input := bufio.NewScanner(os.Stdin)
+	stdin, err := dna.Decompress(os.Stdin)
+	check(err)
 	output := bufio.NewWriter(os.Stdout)
-	push := false
-	for input.Scan() {
-		line := input.Bytes()
-		if line[0] == '>' || line[0] == '@' {
-			line[0] = '>'
-			output.Write(line)
-			output.WriteRune('\n')
-			push = true
-		} else if line[0] == '+' {
-			push = false
-		} else if push {
-			output.Write(line)
-			output.WriteRune('\n')
-		}
-	}
-	output.Flush()
+	check(dna.ReadRecords(stdin, func(name, seq, _ []byte) bool {
+		output.WriteByte('>')
+		output.Write(name)
+		output.WriteByte('\n')
+		output.Write(seq)
+		_, err := output.WriteString("\n")
+		check(err)
+		return true
+	}))
+	check(output.Flush())
 }
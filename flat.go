@@ -0,0 +1,176 @@
+package dna
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// flatRecordSize is the size of a Minimer and its 32 bit count
+const flatRecordSize = minimerBytes + 4
+
+// flatFile is a destination that the header can be rewritten in
+type flatFile interface {
+	io.Writer
+	io.WriterAt
+}
+
+// FlatWriter writes a flat count table: a Header followed by fixed size
+// records of a kmer's packed bases and a little endian 32 bit count, in
+// increasing order. Every kmer of a table has the same length, so the
+// records sort like the kmers they hold.
+type FlatWriter struct {
+	f      flatFile
+	w      *bufio.Writer
+	header Header
+	buf    [flatRecordSize]byte
+}
+
+// NewFlatWriter starts a flat count table of kmers of the given length
+func NewFlatWriter(f flatFile, length int) (*FlatWriter, error) {
+	h := NewHeader(LayoutMinimerCount)
+	h.CountWidth = 4
+	h.MinLength = uint32(length)
+	h.MaxLength = uint32(length)
+	w := &FlatWriter{f: f, w: bufio.NewWriterSize(f, 1<<20), header: h}
+	return w, h.Write(w.w)
+}
+
+// Write appends a kmer and its count
+func (w *FlatWriter) Write(kmer Kmer) error {
+	if kmer.Length != w.header.MinLength {
+		return fmt.Errorf("dna: kmer of length %d written to flat table of length %d", kmer.Length, w.header.MinLength)
+	}
+	for i := range kmer.Kmer {
+		binary.LittleEndian.PutUint64(w.buf[i*8:], kmer.Kmer[i])
+	}
+	binary.LittleEndian.PutUint32(w.buf[minimerBytes:], kmer.Count)
+	w.header.Records++
+	_, err := w.w.Write(w.buf[:])
+	return err
+}
+
+// Close flushes the table and records its size in the header. It does not
+// close the underlying file.
+func (w *FlatWriter) Close() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	return w.header.Rewrite(w.f)
+}
+
+// FlatTable is a read-only, memory mapped flat count table. Lookups and
+// range scans decode records in place without allocating.
+type FlatTable struct {
+	data    []byte
+	records []byte
+	header  Header
+	unmap   func() error
+}
+
+// OpenFlatTable maps a table written by FlatWriter
+func OpenFlatTable(name string) (*FlatTable, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, unmap, err := mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+	t := &FlatTable{data: data, unmap: unmap}
+	if t.header, err = ReadHeader(bytes.NewReader(data)); err == nil {
+		err = t.header.Check(LayoutMinimerCount)
+	}
+	if err == nil && t.header.CountWidth != 4 {
+		err = fmt.Errorf("dna: flat table %s has %d byte counts", name, t.header.CountWidth)
+	}
+	if err == nil && uint64(len(data)-HeaderSize) != t.header.Records*uint64(flatRecordSize) {
+		err = fmt.Errorf("dna: flat table %s is truncated", name)
+	}
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	t.records = data[HeaderSize:]
+	return t, nil
+}
+
+// Close unmaps the table
+func (t *FlatTable) Close() error {
+	return t.unmap()
+}
+
+// Len returns the number of kmers in the table
+func (t *FlatTable) Len() int {
+	return int(t.header.Records)
+}
+
+// Length returns the length of the kmers in the table
+func (t *FlatTable) Length() int {
+	return int(t.header.MinLength)
+}
+
+// kmer decodes record i in place
+func (t *FlatTable) kmer(i int) Kmer {
+	kmer := Kmer{Length: t.header.MinLength}
+	b := t.records[i*flatRecordSize:]
+	for j := range kmer.Kmer {
+		kmer.Kmer[j] = binary.LittleEndian.Uint64(b[j*8:])
+	}
+	kmer.Count = binary.LittleEndian.Uint32(b[minimerBytes:])
+	return kmer
+}
+
+// search returns the index of the first record not below key
+func (t *FlatTable) search(key Minimer) int {
+	return sort.Search(t.Len(), func(i int) bool {
+		return t.kmer(i).Kmer.Cmp(key) >= 0
+	})
+}
+
+// Lookup returns the count of the kmer and whether it is present
+func (t *FlatTable) Lookup(kmer Kmer) (uint32, bool) {
+	if kmer.Length != t.header.MinLength {
+		return 0, false
+	}
+	i := t.search(kmer.Kmer)
+	if i < t.Len() {
+		if found := t.kmer(i); found.Kmer == kmer.Kmer {
+			return found.Count, true
+		}
+	}
+	return 0, false
+}
+
+// Range calls tocall, in order, with the kmers from lo up to but excluding
+// hi until tocall returns false. Both bounds have the table's length.
+func (t *FlatTable) Range(lo, hi Kmer, tocall Kmerhandler) {
+	for i := t.search(lo.Kmer); i < t.Len(); i++ {
+		kmer := t.kmer(i)
+		if kmer.Kmer.Cmp(hi.Kmer) >= 0 || !tocall(kmer) {
+			return
+		}
+	}
+}
+
+// Prefix calls tocall, in order, with every kmer starting with prefix until
+// tocall returns false. A zero length prefix visits the whole table.
+func (t *FlatTable) Prefix(prefix Kmer, tocall Kmerhandler) {
+	if prefix.Length > t.header.MinLength {
+		return
+	}
+	for i := t.search(prefix.Kmer); i < t.Len(); i++ {
+		kmer := t.kmer(i)
+		cut := kmer
+		cut.Truncate(prefix.Length)
+		if cut.Kmer != prefix.Kmer || !tocall(kmer) {
+			return
+		}
+	}
+}
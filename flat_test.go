@@ -0,0 +1,141 @@
+package dna
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeFlat writes a table of the given 4-mers, counting each as its index
+// plus one in the sorted order
+func writeFlat(t *testing.T, seqs ...string) string {
+	t.Helper()
+	var kmers Kmerlist
+	for _, s := range seqs {
+		kmer, err := ParseKmer(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kmers = append(kmers, kmer)
+	}
+	sort.Sort(kmers)
+	name := filepath.Join(t.TempDir(), "table.kct")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewFlatWriter(f, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, kmer := range kmers {
+		kmer.Count = uint32(i + 1)
+		if err := w.Write(kmer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	short, _ := ParseKmer("ACG")
+	if err := w.Write(short); err == nil {
+		t.Error("wrote a 3-mer to a table of 4-mers")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func mustKmer(t *testing.T, s string) Kmer {
+	t.Helper()
+	k, err := ParseKmer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestFlatTable(t *testing.T) {
+	// In the 2-bit order A<C<T<G these sort as AAAA, ACAA, ACCT, ACGT, CAAA,
+	// GGGG
+	table, err := OpenFlatTable(writeFlat(t, "ACGT", "AAAA", "GGGG", "ACCT", "CAAA", "ACAA"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if table.Len() != 6 || table.Length() != 4 {
+		t.Fatalf("table holds %d %d-mers, want 6 4-mers", table.Len(), table.Length())
+	}
+
+	for s, want := range map[string]uint32{"AAAA": 1, "ACAA": 2, "ACGT": 4, "GGGG": 6} {
+		if count, ok := table.Lookup(mustKmer(t, s)); !ok || count != want {
+			t.Errorf("Lookup(%s) = %d, %v, want %d", s, count, ok, want)
+		}
+	}
+	for _, s := range []string{"AAAC", "TTTT", "GGGA", "ACG", "ACGTA"} {
+		if count, ok := table.Lookup(mustKmer(t, s)); ok {
+			t.Errorf("Lookup(%s) found a count of %d", s, count)
+		}
+	}
+
+	collect := func(visit func(Kmerhandler), limit int) []string {
+		var out []string
+		visit(func(k Kmer) bool {
+			out = append(out, k.String())
+			return len(out) < limit
+		})
+		return out
+	}
+	ranges := []struct {
+		lo, hi string
+		want   []string
+	}{
+		{"ACAA", "CAAA", []string{"ACAA", "ACCT", "ACGT"}},
+		{"ACAC", "CAAC", []string{"ACCT", "ACGT", "CAAA"}},
+		{"AAAA", "AAAA", nil},
+		{"CAAC", "GGGG", nil},
+		{"CAAA", "GGGG", []string{"CAAA"}},
+	}
+	for _, r := range ranges {
+		got := collect(func(f Kmerhandler) { table.Range(mustKmer(t, r.lo), mustKmer(t, r.hi), f) }, 100)
+		if !reflect.DeepEqual(got, r.want) {
+			t.Errorf("Range(%s, %s) = %v, want %v", r.lo, r.hi, got, r.want)
+		}
+	}
+	if got := collect(func(f Kmerhandler) { table.Range(mustKmer(t, "AAAA"), mustKmer(t, "GGGG"), f) }, 2); len(got) != 2 {
+		t.Errorf("Range kept going after tocall returned false: %v", got)
+	}
+
+	prefixes := []struct {
+		prefix string
+		want   []string
+	}{
+		{"AC", []string{"ACAA", "ACCT", "ACGT"}},
+		{"ACG", []string{"ACGT"}},
+		{"ACGT", []string{"ACGT"}},
+		{"T", nil},
+		{"ACGTA", nil},
+	}
+	for _, p := range prefixes {
+		got := collect(func(f Kmerhandler) { table.Prefix(mustKmer(t, p.prefix), f) }, 100)
+		if !reflect.DeepEqual(got, p.want) {
+			t.Errorf("Prefix(%s) = %v, want %v", p.prefix, got, p.want)
+		}
+	}
+	if got := collect(func(f Kmerhandler) { table.Prefix(Kmer{}, f) }, 100); len(got) != 6 {
+		t.Errorf("an empty prefix visited %v", got)
+	}
+}
+
+func TestOpenFlatTableEmpty(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "empty.kct")
+	if err := os.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := OpenFlatTable(name)
+	if err == nil || !strings.Contains(err.Error(), "too short for a header") {
+		t.Errorf("got %v, want a short stream error", err)
+	}
+}
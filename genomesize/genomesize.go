@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+)
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+// fitLimit is how far past the first coverage peak counts are fitted, in
+// multiples of that peak. Higher counts are taken as repeats.
+const fitLimit = 10
+
+// maxCount bounds the spectrum. Kmers seen more often are lumped into its
+// last bin, keeping their occurrences for the repeat fraction.
+var maxCount uint = 10000
+
+type estimate struct {
+	K                 uint32  `json:"k"`
+	CoveragePeak      float64 `json:"coverage_peak"`
+	HaploidGenomeSize float64 `json:"haploid_genome_size"`
+	Heterozygosity    float64 `json:"heterozygosity"`
+	ErrorKmerFraction float64 `json:"error_kmer_fraction"`
+	RepeatFraction    float64 `json:"repeat_fraction"`
+	Error             string  `json:"error,omitempty"`
+}
+
+func estimateLength(k uint32, counts map[uint32]uint64) estimate {
+	est := estimate{K: k}
+	var max uint32
+	for c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	if uint(max) > maxCount {
+		max = uint32(maxCount)
+	}
+	spectrum := make([]float64, max+1)
+	// lastOccurrences totals the occurrences of the kmers in the last bin,
+	// those lumped into it included
+	var lastOccurrences float64
+	for c, n := range counts {
+		bin := c
+		if bin > max {
+			bin = max
+		}
+		spectrum[bin] += float64(n)
+		if bin == max {
+			lastOccurrences += float64(n) * float64(c)
+		}
+	}
+	m, err := fitSpectrum(spectrum)
+	if err != nil {
+		est.Error = err.Error()
+		return est
+	}
+	limit := int(math.Ceil(fitLimit * 2 * m.lambda))
+	if limit < len(spectrum) {
+		if m, err = fitSpectrum(spectrum[:limit+1]); err != nil {
+			est.Error = err.Error()
+			return est
+		}
+	}
+
+	// Split every count between the components and attribute counts past
+	// the fitted range to repeats
+	var resp [peaks + 1]float64
+	var distinct, errorKmers, solidOccurrences, repeatOccurrences float64
+	var peakKmers [peaks + 1]float64
+	for c := 1; c < len(spectrum); c++ {
+		n := spectrum[c]
+		if n == 0 {
+			continue
+		}
+		distinct += n
+		occurrences := n * float64(c)
+		if c == len(spectrum)-1 {
+			occurrences = lastOccurrences
+		}
+		if c > limit {
+			solidOccurrences += occurrences
+			repeatOccurrences += occurrences
+			continue
+		}
+		m.responsibilities(c, &resp)
+		errorKmers += n * resp[0]
+		for j := 1; j <= peaks; j++ {
+			peakKmers[j] += n * resp[j]
+			solidOccurrences += occurrences * resp[j]
+			if j > 2 {
+				repeatOccurrences += occurrences * resp[j]
+			}
+		}
+	}
+	est.CoveragePeak = 2 * m.lambda
+	est.HaploidGenomeSize = solidOccurrences / est.CoveragePeak
+	est.ErrorKmerFraction = errorKmers / distinct
+	if solidOccurrences > 0 {
+		est.RepeatFraction = repeatOccurrences / solidOccurrences
+	}
+	// A haploid position holding a heterozygous site yields two kmers at
+	// the first peak, a homozygous one a single kmer at the second
+	if het := peakKmers[1] / 2; het+peakKmers[2] > 0 {
+		p := het / (het + peakKmers[2])
+		est.Heterozygosity = 1 - math.Pow(1-p, 1/float64(k))
+	}
+	return est
+}
+
+func main() {
+	flag.UintVar(&maxCount, "max-count", maxCount, "Largest count given its own bin of the spectrum, higher counts share the last one")
+	flag.Parse()
+	if maxCount < 1 {
+		fmt.Println("Error: -max-count must be at least 1")
+		return
+	}
+	names := flag.Args()
+	if len(names) == 0 {
+		fmt.Println("Error: Must define an input file!")
+		return
+	}
+	hist := dna.NewHistogram()
+	for _, name := range names {
+		if strings.HasSuffix(name, ".tsv") {
+			f, err := os.Open(name)
+			check(err)
+			h, err := dna.ReadHistogramTSV(f)
+			check(err)
+			f.Close()
+			for _, l := range h.Lengths() {
+				for c, n := range h.Spectrum(l) {
+					hist.AddN(l, c, n)
+				}
+			}
+			continue
+		}
+		table, err := counttable.Open(name)
+		check(err)
+		check(table.Prefix(dna.Kmer{}, func(kmer dna.Kmer) bool {
+			hist.Add(kmer.Length, kmer.Count)
+			return true
+		}))
+		check(table.Close())
+	}
+	var estimates []estimate
+	for _, l := range hist.Lengths() {
+		estimates = append(estimates, estimateLength(l, hist.Spectrum(l)))
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	check(enc.Encode(estimates))
+}
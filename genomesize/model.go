@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// peaks is the number of coverage peaks fitted, at 1, 2, 3 and 4 times the
+// per allele coverage. The first two hold heterozygous and homozygous kmers,
+// the others duplicated sequence.
+const peaks = 4
+
+const (
+	maxIterations = 500
+	tolerance     = 1e-9
+)
+
+// model is a mixture of a geometric error component and negative binomial
+// coverage peaks with a shared overdispersion, in the spirit of GenomeScope
+type model struct {
+	lambda         float64
+	overdispersion float64
+	errorMean      float64
+	weights        [peaks + 1]float64
+}
+
+// logPmf returns the log probability of count c under component j, with the
+// zero count excluded since absent kmers are never seen
+func (m *model) logPmf(j, c int) float64 {
+	x := float64(c)
+	if j == 0 {
+		q := 1 - 1/m.errorMean
+		return math.Log(1-q) + (x-1)*math.Log(q)
+	}
+	mu := float64(j) * m.lambda
+	lgc, _ := math.Lgamma(x + 1)
+	if m.overdispersion < 1e-9 {
+		return x*math.Log(mu) - mu - lgc - math.Log1p(-math.Exp(-mu))
+	}
+	r := 1 / m.overdispersion
+	lgcr, _ := math.Lgamma(x + r)
+	lgr, _ := math.Lgamma(r)
+	p0 := r * math.Log(r/(r+mu))
+	return lgcr - lgr - lgc + p0 + x*math.Log(mu/(r+mu)) - math.Log1p(-math.Exp(p0))
+}
+
+// responsibilities fills resp with the posterior of each component for count
+// c and returns the log likelihood of c
+func (m *model) responsibilities(c int, resp *[peaks + 1]float64) float64 {
+	var logs [peaks + 1]float64
+	max := math.Inf(-1)
+	for j := range logs {
+		logs[j] = math.Inf(-1)
+		if m.weights[j] > 0 {
+			logs[j] = math.Log(m.weights[j]) + m.logPmf(j, c)
+		}
+		if logs[j] > max {
+			max = logs[j]
+		}
+	}
+	var total float64
+	for j := range logs {
+		resp[j] = math.Exp(logs[j] - max)
+		total += resp[j]
+	}
+	for j := range resp {
+		resp[j] /= total
+	}
+	return max + math.Log(total)
+}
+
+// fit runs expectation maximisation over spectrum[1:] and returns the log
+// likelihood of the fitted model
+func (m *model) fit(spectrum []float64) float64 {
+	var resp [peaks + 1]float64
+	last := math.Inf(-1)
+	var ll float64
+	for iter := 0; iter < maxIterations; iter++ {
+		var mass [peaks + 1]float64
+		var errorSum, lambdaNum, lambdaDen float64
+		var sq [peaks + 1]float64
+		ll = 0
+		for c := 1; c < len(spectrum); c++ {
+			n := spectrum[c]
+			if n == 0 {
+				continue
+			}
+			ll += n * m.responsibilities(c, &resp)
+			x := float64(c)
+			for j := range resp {
+				w := n * resp[j]
+				mass[j] += w
+				if j == 0 {
+					errorSum += w * x
+					continue
+				}
+				lambdaNum += w * x
+				lambdaDen += w * float64(j)
+				d := x - float64(j)*m.lambda
+				sq[j] += w * d * d
+			}
+		}
+		var total float64
+		for _, w := range mass {
+			total += w
+		}
+		for j := range mass {
+			m.weights[j] = mass[j] / total
+		}
+		if mass[0] > 0 {
+			m.errorMean = math.Max(errorSum/mass[0], 1+1e-6)
+		}
+		if lambdaDen > 0 {
+			m.lambda = lambdaNum / lambdaDen
+		}
+		var excess, excessMass float64
+		for j := 1; j <= peaks; j++ {
+			if mass[j] == 0 {
+				continue
+			}
+			mu := float64(j) * m.lambda
+			excess += mass[j] * (sq[j]/mass[j] - mu) / (mu * mu)
+			excessMass += mass[j]
+		}
+		if excessMass > 0 {
+			m.overdispersion = math.Min(math.Max(excess/excessMass, 0), 1)
+		}
+		if math.Abs(ll-last) <= tolerance*math.Abs(ll) {
+			break
+		}
+		last = ll
+	}
+	return ll
+}
+
+// fitSpectrum finds the first coverage peak past the error valley and fits
+// the model starting from it both as the homozygous and the heterozygous
+// peak, keeping the more likely fit
+func fitSpectrum(spectrum []float64) (*model, error) {
+	valley := 1
+	for valley+1 < len(spectrum) && spectrum[valley+1] <= spectrum[valley] {
+		valley++
+	}
+	peak := 0
+	for c := valley + 1; c < len(spectrum); c++ {
+		if peak == 0 || spectrum[c] > spectrum[peak] {
+			peak = c
+		}
+	}
+	if peak == 0 {
+		return nil, errors.New("spectrum has no coverage peak")
+	}
+	var errorKmers, errorSum, total float64
+	for c := 1; c < len(spectrum); c++ {
+		total += spectrum[c]
+		if c <= valley {
+			errorKmers += spectrum[c]
+			errorSum += spectrum[c] * float64(c)
+		}
+	}
+	var best *model
+	bestLL := math.Inf(-1)
+	for _, lambda := range []float64{float64(peak) / 2, float64(peak)} {
+		m := &model{lambda: lambda, overdispersion: 0.01, errorMean: math.Max(errorSum/errorKmers, 1+1e-6)}
+		m.weights[0] = errorKmers / total
+		rest := 1 - m.weights[0]
+		m.weights[1], m.weights[2] = rest*0.45, rest*0.45
+		m.weights[3], m.weights[4] = rest*0.05, rest*0.05
+		if ll := m.fit(spectrum); ll > bestLL {
+			best, bestLL = m, ll
+		}
+	}
+	return best, nil
+}
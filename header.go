@@ -0,0 +1,154 @@
+package dna
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// headerMagic starts every binary kmer stream
+const headerMagic = "SKEK"
+
+// HeaderVersion is the stream format version written by this package
+const HeaderVersion = 1
+
+// HeaderSize is the encoded size of a Header
+const HeaderSize = 40
+
+// Layout describes the records following a Header
+type Layout uint8
+
+const (
+	// LayoutMinimer records are sentinel terminated Minimers, see Encoder
+	LayoutMinimer Layout = iota + 1
+	// LayoutMinimerCount records are a Minimer followed by a count of
+	// CountWidth bytes
+	LayoutMinimerCount
+	// LayoutPacked records are single words of up to 31 base pairs with a
+	// marker below the oldest base pair, see PackedWriter
+	LayoutPacked
+)
+
+func (l Layout) String() string {
+	switch l {
+	case LayoutMinimer:
+		return "minimer"
+	case LayoutMinimerCount:
+		return "minimer+count"
+	case LayoutPacked:
+		return "packed"
+	}
+	return fmt.Sprintf("layout(%d)", uint8(l))
+}
+
+// Header describes a binary kmer stream. Records and Checksum are zero
+// when the writer could not know them.
+type Header struct {
+	Version    uint16
+	Layout     Layout
+	KmerWords  uint8
+	CountWidth uint8
+	MinLength  uint32
+	MaxLength  uint32
+	Records    uint64
+	// Checksum is the CRC-64 (ECMA) of the source the records came from
+	Checksum uint64
+}
+
+// NewHeader returns a header for records of this build
+func NewHeader(layout Layout) Header {
+	return Header{Version: HeaderVersion, Layout: layout, KmerWords: uint8(kmerwords)}
+}
+
+// Write encodes the header to w
+func (h Header) Write(w io.Writer) error {
+	_, err := w.Write(h.encode())
+	return err
+}
+
+// Rewrite replaces the header at the start of a stream, filling in Records
+// and Checksum once they are known
+func (h Header) Rewrite(w io.WriterAt) error {
+	_, err := w.WriteAt(h.encode(), 0)
+	return err
+}
+
+func (h Header) encode() []byte {
+	b := make([]byte, HeaderSize)
+	copy(b, headerMagic)
+	binary.LittleEndian.PutUint16(b[4:], h.Version)
+	b[6] = byte(h.Layout)
+	b[7] = h.KmerWords
+	b[8] = h.CountWidth
+	binary.LittleEndian.PutUint32(b[12:], h.MinLength)
+	binary.LittleEndian.PutUint32(b[16:], h.MaxLength)
+	binary.LittleEndian.PutUint64(b[24:], h.Records)
+	binary.LittleEndian.PutUint64(b[32:], h.Checksum)
+	return b
+}
+
+// ReadHeader reads the header starting a stream and checks its magic and
+// version
+func ReadHeader(r io.Reader) (Header, error) {
+	b := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return Header{}, fmt.Errorf("dna: stream is too short for a header")
+		}
+		return Header{}, err
+	}
+	if string(b[:4]) != headerMagic {
+		return Header{}, fmt.Errorf("dna: not a kmer stream (magic %q)", b[:4])
+	}
+	h := Header{
+		Version:    binary.LittleEndian.Uint16(b[4:]),
+		Layout:     Layout(b[6]),
+		KmerWords:  b[7],
+		CountWidth: b[8],
+		MinLength:  binary.LittleEndian.Uint32(b[12:]),
+		MaxLength:  binary.LittleEndian.Uint32(b[16:]),
+		Records:    binary.LittleEndian.Uint64(b[24:]),
+		Checksum:   binary.LittleEndian.Uint64(b[32:]),
+	}
+	if h.Version != HeaderVersion {
+		return h, fmt.Errorf("dna: kmer stream version %d, expected %d", h.Version, HeaderVersion)
+	}
+	return h, nil
+}
+
+// Check verifies that the stream holds records of the given layout that
+// this build can read
+func (h Header) Check(layout Layout) error {
+	if h.Layout != layout {
+		return fmt.Errorf("dna: stream holds %v records, expected %v", h.Layout, layout)
+	}
+	maxLength, words := MaxLength, kmerwords
+	if layout == LayoutPacked {
+		maxLength, words = packedMaxLength, 1
+	}
+	if uint(h.KmerWords) != words {
+		return fmt.Errorf("dna: stream holds %d word kmers but this build uses %d, see the kmer63 and kmer127 build tags", h.KmerWords, words)
+	}
+	if h.MinLength > h.MaxLength || h.MaxLength > uint32(maxLength) {
+		return fmt.Errorf("dna: stream holds kmers of %d to %d base pairs, this build reads up to %d", h.MinLength, h.MaxLength, maxLength)
+	}
+	if hasCount := layout == LayoutMinimerCount; hasCount != (h.CountWidth != 0) || h.CountWidth > 8 {
+		return fmt.Errorf("dna: invalid count width %d for %v records", h.CountWidth, layout)
+	}
+	return nil
+}
+
+// WriteHeader starts the stream with a header
+func (e *Encoder) WriteHeader(h Header) error {
+	return h.Write(e.w)
+}
+
+// ReadHeader reads the header starting the stream and checks that it holds
+// Minimers of this build
+func (d *Decoder) ReadHeader() (Header, error) {
+	h, err := ReadHeader(d.r)
+	if err != nil {
+		return h, err
+	}
+	return h, h.Check(LayoutMinimer)
+}
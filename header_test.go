@@ -0,0 +1,55 @@
+package dna
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHeaderCheck(t *testing.T) {
+	minimer := func(min, max uint32) Header {
+		h := NewHeader(LayoutMinimer)
+		h.MinLength, h.MaxLength = min, max
+		return h
+	}
+	counted := func(width uint8) Header {
+		h := NewHeader(LayoutMinimerCount)
+		h.CountWidth, h.MinLength, h.MaxLength = width, 21, 21
+		return h
+	}
+	wider := minimer(1, 4*32-1)
+	wider.KmerWords = 4
+	longer := minimer(1, uint32(MaxLength)+1)
+	packed := NewHeader(LayoutPacked)
+	packed.KmerWords, packed.MinLength, packed.MaxLength = 1, 1, packedMaxLength
+	tests := []struct {
+		name   string
+		h      Header
+		layout Layout
+		ok     bool
+	}{
+		{"minimer", minimer(8, uint32(MaxLength)), LayoutMinimer, true},
+		{"wrong layout", minimer(8, 20), LayoutMinimerCount, false},
+		{"wider kmers", wider, LayoutMinimer, kmerwords == 4},
+		{"longer kmers", longer, LayoutMinimer, false},
+		{"inverted lengths", minimer(20, 8), LayoutMinimer, false},
+		{"count on minimers", func() Header { h := minimer(8, 20); h.CountWidth = 4; return h }(), LayoutMinimer, false},
+		{"count", counted(4), LayoutMinimerCount, true},
+		{"no count", counted(0), LayoutMinimerCount, false},
+		{"oversized count", counted(9), LayoutMinimerCount, false},
+		{"packed", packed, LayoutPacked, true},
+		{"long packed", func() Header { h := packed; h.MaxLength = 32; return h }(), LayoutPacked, false},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.h.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		h, err := ReadHeader(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := h.Check(tt.layout); (err == nil) != tt.ok {
+			t.Errorf("%s: Check gave %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package dna
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Histogram collects the abundance spectrum of each kmer length: how many
+// distinct kmers occur once, twice and so on. Its maps are unguarded, so
+// callers adding from several goroutines need their own lock.
+type Histogram struct {
+	lengths map[uint32]map[uint32]uint64
+}
+
+// NewHistogram creates an empty Histogram
+func NewHistogram() *Histogram {
+	return &Histogram{make(map[uint32]map[uint32]uint64)}
+}
+
+// Add records a distinct kmer of the given length and count
+func (h *Histogram) Add(length, count uint32) {
+	h.AddN(length, count, 1)
+}
+
+// AddN records n distinct kmers of the given length and count
+func (h *Histogram) AddN(length, count uint32, n uint64) {
+	spectrum, ok := h.lengths[length]
+	if !ok {
+		spectrum = make(map[uint32]uint64)
+		h.lengths[length] = spectrum
+	}
+	spectrum[count] += n
+}
+
+// Lengths returns the recorded kmer lengths in increasing order
+func (h *Histogram) Lengths() []uint32 {
+	lengths := make([]uint32, 0, len(h.lengths))
+	for l := range h.lengths {
+		lengths = append(lengths, l)
+	}
+	sort.Slice(lengths, func(i, j int) bool { return lengths[i] < lengths[j] })
+	return lengths
+}
+
+// Spectrum returns the number of distinct kmers of a length by count
+func (h *Histogram) Spectrum(length uint32) map[uint32]uint64 {
+	return h.lengths[length]
+}
+
+// WriteTSV writes the histogram as length, abundance and distinct kmer
+// columns, sorted by length and abundance
+func (h *Histogram) WriteTSV(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "length\tabundance\tkmers")
+	for _, l := range h.Lengths() {
+		spectrum := h.lengths[l]
+		counts := make([]uint32, 0, len(spectrum))
+		for c := range spectrum {
+			counts = append(counts, c)
+		}
+		sort.Slice(counts, func(i, j int) bool { return counts[i] < counts[j] })
+		for _, c := range counts {
+			fmt.Fprintf(out, "%d\t%d\t%d\n", l, c, spectrum[c])
+		}
+	}
+	return out.Flush()
+}
+
+// ReadHistogramTSV reads a histogram written by WriteTSV
+func ReadHistogramTSV(r io.Reader) (*Histogram, error) {
+	h := NewHistogram()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if line == 1 {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("dna: histogram line %d has %d columns", line, len(fields))
+		}
+		var values [3]uint64
+		for i, field := range fields {
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("dna: histogram line %d: %v", line, err)
+			}
+			values[i] = v
+		}
+		h.AddN(uint32(values[0]), uint32(values[1]), values[2])
+	}
+	return h, scanner.Err()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+	"github.com/ericpauley/go-hdf5"
+)
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+// addPartials adds every sector table of a prefixcounting output. Sectors
+// hold disjoint kmers, so their counts are final. Outputs counted with
+// -canonical are rejected by main since their sectors are not disjoint.
+func addPartials(hist *dna.Histogram, group *hdf5.Group) {
+	check(counttable.ReadPartials(group, 0, func(kmer dna.Kmer) {
+		hist.Add(kmer.Length, kmer.Count)
+	}))
+}
+
+func main() {
+	var foutput string
+	var mate int
+	flag.StringVar(&foutput, "out", "", "The output filename (default stdout)")
+	flag.IntVar(&mate, "mate", 0, "Take the histogram of read 1 or 2 of paired prefixcounting outputs (0 for both mates together)")
+	flag.Parse()
+	names := flag.Args()
+	if len(names) == 0 {
+		fmt.Println("Error: Must define an input file!")
+		return
+	}
+	if mate < 0 || mate > 2 {
+		fmt.Println("Error: -mate must be 0, 1 or 2")
+		return
+	}
+	hist := dna.NewHistogram()
+	for _, name := range names {
+		if _, err := counttable.LengthFromName(name); err == nil {
+			table, err := counttable.Open(name)
+			check(err)
+			check(table.Prefix(dna.Kmer{}, func(kmer dna.Kmer) bool {
+				hist.Add(kmer.Length, kmer.Count)
+				return true
+			}))
+			check(table.Close())
+			continue
+		}
+		h5, err := hdf5.OpenFile(name, hdf5.F_ACC_RDONLY)
+		check(err)
+		if marker, err := h5.OpenGroup("canonical"); err == nil {
+			marker.Close()
+			check(fmt.Errorf("%s was counted with -canonical, take the histogram of its merged tables instead", name))
+		}
+		groups, err := counttable.OpenPartials(h5, mate, true)
+		if err != nil {
+			check(fmt.Errorf("%s %v", name, err))
+		}
+		for _, group := range groups {
+			addPartials(hist, group)
+			group.Close()
+		}
+		h5.Close()
+	}
+	out := os.Stdout
+	if foutput != "" {
+		f, err := os.Create(foutput)
+		check(err)
+		defer f.Close()
+		out = f
+	}
+	check(hist.WriteTSV(out))
+}
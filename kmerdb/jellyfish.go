@@ -0,0 +1,194 @@
+package kmerdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/ericpauley/dna"
+)
+
+// jellyfishHeader is the JSON header of a Jellyfish binary dump. On disk it
+// follows its length written as nine decimal digits.
+type jellyfishHeader struct {
+	Format     string   `json:"format"`
+	KeyLen     int      `json:"key_len"`
+	CounterLen int      `json:"counter_len"`
+	Canonical  bool     `json:"canonical"`
+	Cmdline    []string `json:"cmdline,omitempty"`
+}
+
+// isJellyfish reports whether r starts with a Jellyfish binary header
+func isJellyfish(r *bufio.Reader) bool {
+	b, err := r.Peek(10)
+	if err != nil || b[9] != '{' {
+		return false
+	}
+	for _, c := range b[:9] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadJellyfish reads a binary dump written by jellyfish count or dump and
+// reports whether it was counted with -C. Keys are stored little endian,
+// the last base in the low bits, followed by a little endian counter.
+func ReadJellyfish(r io.Reader) ([]dna.Kmer, bool, error) {
+	var size [9]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, false, fmt.Errorf("kmerdb: reading Jellyfish header: %v", err)
+	}
+	n, err := strconv.Atoi(string(size[:]))
+	if err != nil {
+		return nil, false, fmt.Errorf("kmerdb: not a Jellyfish binary dump")
+	}
+	raw := make([]byte, n)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, false, fmt.Errorf("kmerdb: reading Jellyfish header: %v", err)
+	}
+	var h jellyfishHeader
+	if err := json.NewDecoder(bytes.NewReader(raw)).Decode(&h); err != nil {
+		return nil, false, fmt.Errorf("kmerdb: parsing Jellyfish header: %v", err)
+	}
+	if h.Format != "binary/sorted" && h.Format != "binary" {
+		return nil, false, fmt.Errorf("kmerdb: unsupported Jellyfish format %q", h.Format)
+	}
+	k := h.KeyLen / 2
+	if k < 1 || k > dna.MaxLength {
+		return nil, false, fmt.Errorf("kmerdb: Jellyfish %d-mers are not supported by this build", k)
+	}
+	if h.CounterLen < 1 || h.CounterLen > 8 {
+		return nil, false, fmt.Errorf("kmerdb: invalid Jellyfish counter length %d", h.CounterLen)
+	}
+	keyBytes := (h.KeyLen + 7) / 8
+	record := make([]byte, keyBytes+h.CounterLen)
+	br := bufio.NewReaderSize(r, 1<<20)
+	var kmers []dna.Kmer
+	for {
+		if _, err := io.ReadFull(br, record); err == io.EOF {
+			return kmers, h.Canonical, nil
+		} else if err != nil {
+			return nil, false, fmt.Errorf("kmerdb: reading Jellyfish records: %v", err)
+		}
+		kmer := build(k, func(i int) uint64 {
+			p := 2 * (k - 1 - i)
+			return acgt(uint64(record[p/8]>>(p%8)) & 3)
+		})
+		kmer.Count = clampCount(record[keyBytes:])
+		kmers = append(kmers, kmer)
+	}
+}
+
+// clampCount reads a little endian counter, saturating at 32 bits
+func clampCount(b []byte) uint32 {
+	var count uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		count = count<<8 | uint64(b[i])
+	}
+	if count > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(count)
+}
+
+// ReadJellyfishText reads the output of jellyfish dump, either in column
+// form (-c, a kmer and count per line) or as FASTA with counts as names
+func ReadJellyfishText(r io.Reader) ([]dna.Kmer, error) {
+	scanner := bufio.NewScanner(r)
+	var kmers []dna.Kmer
+	var count uint64
+	fasta := false
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		if fields[0][0] == '>' {
+			fasta = true
+			count, err = strconv.ParseUint(string(fields[0][1:]), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("kmerdb: line %d: invalid count", line)
+			}
+			continue
+		}
+		if !fasta {
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("kmerdb: line %d: expected a kmer and a count", line)
+			}
+			if count, err = strconv.ParseUint(string(fields[1]), 10, 32); err != nil {
+				return nil, fmt.Errorf("kmerdb: line %d: invalid count", line)
+			}
+		}
+		kmer, err := dna.ParseKmer(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("kmerdb: line %d: %v", line, err)
+		}
+		kmer.Count = uint32(count)
+		kmers = append(kmers, kmer)
+	}
+	return kmers, scanner.Err()
+}
+
+// WriteJellyfish writes kmers of length k as a Jellyfish binary dump with
+// 32 bit counters
+func WriteJellyfish(w io.Writer, k int, canonical bool, kmers []dna.Kmer) error {
+	raw, err := json.Marshal(jellyfishHeader{
+		Format:     "binary/sorted",
+		KeyLen:     2 * k,
+		CounterLen: 4,
+		Canonical:  canonical,
+		Cmdline:    []string{"ske", "export"},
+	})
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriterSize(w, 1<<20)
+	if _, err := fmt.Fprintf(bw, "%09d", len(raw)); err != nil {
+		return err
+	}
+	if _, err := bw.Write(raw); err != nil {
+		return err
+	}
+	keyBytes := (2*k + 7) / 8
+	record := make([]byte, keyBytes+4)
+	for _, kmer := range kmers {
+		if int(kmer.Length) != k {
+			return fmt.Errorf("kmerdb: kmer of length %d in a %d-mer database", kmer.Length, k)
+		}
+		for i := range record {
+			record[i] = 0
+		}
+		for i := 0; i < k; i++ {
+			p := 2 * (k - 1 - i)
+			record[p/8] |= byte(acgt(base(kmer, i)) << (p % 8))
+		}
+		record[keyBytes] = byte(kmer.Count)
+		record[keyBytes+1] = byte(kmer.Count >> 8)
+		record[keyBytes+2] = byte(kmer.Count >> 16)
+		record[keyBytes+3] = byte(kmer.Count >> 24)
+		if _, err := bw.Write(record); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteJellyfishText writes kmers in the column form of jellyfish dump -c
+func WriteJellyfishText(w io.Writer, kmers []dna.Kmer) error {
+	bw := bufio.NewWriterSize(w, 1<<20)
+	for _, kmer := range kmers {
+		if _, err := fmt.Fprintf(bw, "%v %d\n", kmer, kmer.Count); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
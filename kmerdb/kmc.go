@@ -0,0 +1,206 @@
+package kmerdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/ericpauley/dna"
+)
+
+// kmcHeaderSize is the size of the version 0 header at the end of a
+// .kmc_pre file, reserved bytes included
+const kmcHeaderSize = 64
+
+// kmcHeader holds the parameters of a KMC database
+type kmcHeader struct {
+	KmerLength  uint32
+	Mode        uint32
+	CounterSize uint32
+	LutLength   uint32
+	MinCount    uint32
+	MaxCount    uint32
+	TotalKmers  uint64
+}
+
+// ReadKMC reads a version 0 (KMC 1 style) database from prefix.kmc_pre and
+// prefix.kmc_suf and reports whether it holds canonical kmers. The prefix
+// file holds a lookup table of where the suffixes of every LutLength base
+// prefix start; the suffix file holds the remaining bases, packed big
+// endian and right aligned, and a counter.
+func ReadKMC(prefix string) ([]dna.Kmer, bool, error) {
+	pre, err := os.ReadFile(prefix + ".kmc_pre")
+	if err != nil {
+		return nil, false, err
+	}
+	if len(pre) < 12+kmcHeaderSize || string(pre[:4]) != "KMCP" || string(pre[len(pre)-4:]) != "KMCP" {
+		return nil, false, fmt.Errorf("kmerdb: %s.kmc_pre is not a KMC database", prefix)
+	}
+	if version := binary.LittleEndian.Uint32(pre[len(pre)-12:]); version != 0 {
+		return nil, false, fmt.Errorf("kmerdb: KMC database version %#x is not supported", version)
+	}
+	offset := int(binary.LittleEndian.Uint32(pre[len(pre)-8:]))
+	if offset > len(pre)-12 {
+		return nil, false, fmt.Errorf("kmerdb: %s.kmc_pre has a corrupt header", prefix)
+	}
+	hb := pre[len(pre)-8-offset:]
+	h := kmcHeader{
+		KmerLength:  binary.LittleEndian.Uint32(hb[0:]),
+		Mode:        binary.LittleEndian.Uint32(hb[4:]),
+		CounterSize: binary.LittleEndian.Uint32(hb[8:]),
+		LutLength:   binary.LittleEndian.Uint32(hb[12:]),
+		MinCount:    binary.LittleEndian.Uint32(hb[16:]),
+		MaxCount:    binary.LittleEndian.Uint32(hb[20:]),
+		TotalKmers:  binary.LittleEndian.Uint64(hb[24:]),
+	}
+	k := int(h.KmerLength)
+	if k < 1 || k > dna.MaxLength {
+		return nil, false, fmt.Errorf("kmerdb: KMC %d-mers are not supported by this build", k)
+	}
+	if h.Mode != 0 {
+		return nil, false, fmt.Errorf("kmerdb: KMC quality-aware counters are not supported")
+	}
+	if h.CounterSize > 8 || h.LutLength > h.KmerLength {
+		return nil, false, fmt.Errorf("kmerdb: %s.kmc_pre has a corrupt header", prefix)
+	}
+	lut := pre[4 : len(pre)-8-offset]
+	if len(lut) != 8<<(2*h.LutLength) {
+		return nil, false, fmt.Errorf("kmerdb: %s.kmc_pre has a lookup table of %d bytes", prefix, len(lut))
+	}
+	suf, err := os.ReadFile(prefix + ".kmc_suf")
+	if err != nil {
+		return nil, false, err
+	}
+	if len(suf) < 8 || string(suf[:4]) != "KMCS" || string(suf[len(suf)-4:]) != "KMCS" {
+		return nil, false, fmt.Errorf("kmerdb: %s.kmc_suf is not a KMC database", prefix)
+	}
+	suf = suf[4 : len(suf)-4]
+	lutLength := int(h.LutLength)
+	s := k - lutLength
+	sufBytes := (s + 3) / 4
+	record := sufBytes + int(h.CounterSize)
+	if uint64(len(suf)) != h.TotalKmers*uint64(record) {
+		return nil, false, fmt.Errorf("kmerdb: %s.kmc_suf holds %d bytes for %d kmers", prefix, len(suf), h.TotalKmers)
+	}
+	kmers := make([]dna.Kmer, 0, h.TotalKmers)
+	entries := len(lut) / 8
+	for p := 0; p < entries; p++ {
+		start := binary.LittleEndian.Uint64(lut[8*p:])
+		end := h.TotalKmers
+		if p+1 < entries {
+			end = binary.LittleEndian.Uint64(lut[8*p+8:])
+		}
+		if start > end || end > h.TotalKmers {
+			return nil, false, fmt.Errorf("kmerdb: %s.kmc_pre has a corrupt lookup table", prefix)
+		}
+		for j := start; j < end; j++ {
+			rec := suf[j*uint64(record):]
+			kmer := build(k, func(i int) uint64 {
+				if i < lutLength {
+					return acgt(uint64(p>>(2*(lutLength-1-i))) & 3)
+				}
+				q := 2 * (s - 1 - (i - lutLength))
+				return acgt(uint64(rec[sufBytes-1-q/8]>>(q%8)) & 3)
+			})
+			kmer.Count = clampCount(rec[sufBytes:record])
+			kmers = append(kmers, kmer)
+		}
+	}
+	return kmers, hb[32] == 0, nil
+}
+
+// kmcLutLength picks a prefix length that leaves a whole number of suffix
+// bytes while keeping the lookup table small
+func kmcLutLength(k int) int {
+	lut := k % 4
+	for lut+4 <= k && lut+4 <= 8 {
+		lut += 4
+	}
+	return lut
+}
+
+// WriteKMC writes kmers of length k as a version 0 KMC database with 32 bit
+// counters to prefix.kmc_pre and prefix.kmc_suf
+func WriteKMC(prefix string, k int, canonical bool, kmers []dna.Kmer) error {
+	// Recode the bases so kmers sort in KMC's A<C<G<T order
+	keys := make([]dna.Kmer, len(kmers))
+	for n, kmer := range kmers {
+		if int(kmer.Length) != k {
+			return fmt.Errorf("kmerdb: kmer of length %d in a %d-mer database", kmer.Length, k)
+		}
+		keys[n] = build(k, func(i int) uint64 { return acgt(base(kmer, i)) })
+		keys[n].Count = kmer.Count
+	}
+	sort.Sort(dna.Kmerlist(keys))
+	lutLength := kmcLutLength(k)
+	s := k - lutLength
+	sufBytes := s / 4
+	lut := make([]uint64, 1<<(2*uint(lutLength)))
+	suf := make([]byte, 4, 8+len(keys)*(sufBytes+4))
+	copy(suf, "KMCS")
+	h := kmcHeader{
+		KmerLength:  uint32(k),
+		CounterSize: 4,
+		LutLength:   uint32(lutLength),
+		MinCount:    math.MaxUint32,
+		TotalKmers:  uint64(len(keys)),
+	}
+	for _, key := range keys {
+		p := 0
+		for i := 0; i < lutLength; i++ {
+			p = p<<2 | int(base(key, i))
+		}
+		if p+1 < len(lut) {
+			lut[p+1]++
+		}
+		for b := 0; b < sufBytes; b++ {
+			var v byte
+			for i := 0; i < 4; i++ {
+				v = v<<2 | byte(base(key, lutLength+4*b+i))
+			}
+			suf = append(suf, v)
+		}
+		suf = binary.LittleEndian.AppendUint32(suf, key.Count)
+		if key.Count < h.MinCount {
+			h.MinCount = key.Count
+		}
+		if key.Count > h.MaxCount {
+			h.MaxCount = key.Count
+		}
+	}
+	if len(keys) == 0 {
+		h.MinCount = 0
+	}
+	// Entries hold the index of the first kmer with each prefix
+	for p := 1; p < len(lut); p++ {
+		lut[p] += lut[p-1]
+	}
+	suf = append(suf, "KMCS"...)
+	pre := make([]byte, 4, 4+8*len(lut)+kmcHeaderSize+8)
+	copy(pre, "KMCP")
+	for _, v := range lut {
+		pre = binary.LittleEndian.AppendUint64(pre, v)
+	}
+	hb := make([]byte, kmcHeaderSize)
+	binary.LittleEndian.PutUint32(hb[0:], h.KmerLength)
+	binary.LittleEndian.PutUint32(hb[4:], h.Mode)
+	binary.LittleEndian.PutUint32(hb[8:], h.CounterSize)
+	binary.LittleEndian.PutUint32(hb[12:], h.LutLength)
+	binary.LittleEndian.PutUint32(hb[16:], h.MinCount)
+	binary.LittleEndian.PutUint32(hb[20:], h.MaxCount)
+	binary.LittleEndian.PutUint64(hb[24:], h.TotalKmers)
+	// KMC stores whether only one strand was counted, canonical databases
+	// count both
+	if !canonical {
+		hb[32] = 1
+	}
+	pre = append(pre, hb...)
+	pre = binary.LittleEndian.AppendUint32(pre, kmcHeaderSize)
+	pre = append(pre, "KMCP"...)
+	if err := os.WriteFile(prefix+".kmc_pre", pre, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(prefix+".kmc_suf", suf, 0644)
+}
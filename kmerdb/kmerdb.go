@@ -0,0 +1,118 @@
+// Package kmerdb reads and writes the count databases of Jellyfish and KMC
+// so their counts can be merged with ours and ours can be used with their
+// tools.
+package kmerdb
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ericpauley/dna"
+)
+
+const batchSize = 10000
+
+// acgt converts between the A=0, C=1, T=2, G=3 base codes of the dna package
+// and the A=0, C=1, G=2, T=3 codes of Jellyfish and KMC. It is its own
+// inverse.
+func acgt(b uint64) uint64 {
+	return b ^ b>>1
+}
+
+// base returns the code of base i of the kmer
+func base(kmer dna.Kmer, i int) uint64 {
+	return kmer.Kmer[i/32] >> (62 - 2*uint(i%32)) & 3
+}
+
+// build returns the kmer of length k whose base i has the code code(i)
+func build(k int, code func(i int) uint64) dna.Kmer {
+	var kmer dna.Kmer
+	for i := k - 1; i >= 0; i-- {
+		kmer.Push(code(i))
+	}
+	return kmer
+}
+
+// Stream sorts the kmers into the order of the dna package, which differs
+// from that of Jellyfish and KMC, and sends them in batches
+func Stream(kmers []dna.Kmer) chan []dna.Kmer {
+	sort.Sort(dna.Kmerlist(kmers))
+	c := make(chan []dna.Kmer, 1)
+	go func() {
+		for len(kmers) > 0 {
+			n := batchSize
+			if n > len(kmers) {
+				n = len(kmers)
+			}
+			c <- kmers[:n:n]
+			kmers = kmers[n:]
+		}
+		close(c)
+	}()
+	return c
+}
+
+// kmcPrefix returns the common prefix of a KMC database if name is one
+func kmcPrefix(name string) (string, bool) {
+	switch filepath.Ext(name) {
+	case ".kmc_pre", ".kmc_suf":
+		return strings.TrimSuffix(name, filepath.Ext(name)), true
+	}
+	if _, err := os.Stat(name + ".kmc_pre"); err == nil {
+		return name, true
+	}
+	return "", false
+}
+
+// Read loads a KMC database, named by either of its files or their common
+// prefix, or a Jellyfish binary or text dump, which may be compressed. It
+// reports whether the database holds canonical kmers, which text dumps do
+// not record.
+func Read(name string) ([]dna.Kmer, bool, error) {
+	if prefix, ok := kmcPrefix(name); ok {
+		return ReadKMC(prefix)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	in, err := dna.Decompress(f)
+	if err != nil {
+		return nil, false, err
+	}
+	defer in.Close()
+	r := bufio.NewReader(in)
+	if isJellyfish(r) {
+		return ReadJellyfish(r)
+	}
+	kmers, err := ReadJellyfishText(r)
+	return kmers, false, err
+}
+
+// Canonicalize replaces every kmer by its canonical form, in the A<C<G<T
+// order Jellyfish and KMC use, and sums the counts of kmers that meet. The
+// result is in the order of the dna package.
+func Canonicalize(kmers []dna.Kmer) []dna.Kmer {
+	for i := range kmers {
+		kmers[i] = kmers[i].Canonical()
+	}
+	sort.Sort(dna.Kmerlist(kmers))
+	out := kmers[:0]
+	for _, kmer := range kmers {
+		if n := len(out); n > 0 && out[n-1].Cmp(kmer) == 0 {
+			if sum := uint64(out[n-1].Count) + uint64(kmer.Count); sum > math.MaxUint32 {
+				out[n-1].Count = math.MaxUint32
+			} else {
+				out[n-1].Count = uint32(sum)
+			}
+			continue
+		}
+		out = append(out, kmer)
+	}
+	return out
+}
@@ -0,0 +1,8 @@
+//go:build !kmer63 && !kmer127
+
+package dna
+
+// kmerwords is the number of 64 bit words in a Minimer. The default build
+// holds kmers of up to 31 base pairs, build with the kmer63 or kmer127 tags
+// for longer kmers.
+const kmerwords uint = 1
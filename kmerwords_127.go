@@ -0,0 +1,5 @@
+//go:build kmer127
+
+package dna
+
+const kmerwords uint = 4
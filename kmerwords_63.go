@@ -0,0 +1,5 @@
+//go:build kmer63 && !kmer127
+
+package dna
+
+const kmerwords uint = 2
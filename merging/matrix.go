@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/setops"
+)
+
+// matrixRow accumulates the per sample counts of the current kmer of one
+// length and writes finished rows to that length's matrix
+type matrixRow struct {
+	kmer    dna.Kmer
+	counts  []uint32
+	present bool
+	file    *os.File
+	w       *bufio.Writer
+}
+
+// flush writes the current row if any sample reaches its min abundance
+func (r *matrixRow) flush(mins []uint32) {
+	if !r.present {
+		return
+	}
+	r.present = false
+	keep := false
+	for i, c := range r.counts {
+		if c < mins[i] {
+			r.counts[i] = 0
+		}
+		keep = keep || r.counts[i] > 0
+	}
+	if !keep {
+		return
+	}
+	r.w.WriteString(r.kmer.String())
+	for _, c := range r.counts {
+		r.w.WriteByte('\t')
+		r.w.WriteString(strconv.FormatUint(uint64(c), 10))
+	}
+	r.w.WriteByte('\n')
+}
+
+// writeMatrix writes matrix{L}.tsv, or matrix{L}.r1.tsv for -mate 1, with a
+// row per kmer and a column per sample, where each source is the sorted
+// stream of one input file
+func writeMatrix(sources []chan []dna.Kmer, names []string, mins []uint32) {
+	ops := make([]setops.Operand, len(sources))
+	for i := range sources {
+		ops[i].Stream = sources[i]
+	}
+	header := "kmer"
+	for _, name := range names {
+		header += "\t" + strings.TrimSuffix(filepath.Base(name), ".h5")
+	}
+	rows := make([]matrixRow, maxsize+1)
+	for i := maxsize; i >= minsize; i-- {
+		f, err := os.Create("matrix" + strconv.Itoa(int(i)) + dna.MateSuffix(mate) + ".tsv")
+		check(err)
+		rows[i].file = f
+		rows[i].w = bufio.NewWriter(f)
+		rows[i].counts = make([]uint32, len(sources))
+		_, err = fmt.Fprintln(rows[i].w, header)
+		check(err)
+	}
+	setops.Join(ops, func(kmer dna.Kmer, counts []uint32) bool {
+		shortest := uint32(minsize)
+		if canonical {
+			shortest = kmer.Length
+		}
+		for l := kmer.Length; l >= shortest; l-- {
+			kmer.Truncate(l)
+			r := &rows[l]
+			if r.present && r.kmer.Kmer == kmer.Kmer {
+				for i, c := range counts {
+					r.counts[i] += c
+				}
+				continue
+			}
+			r.flush(mins)
+			r.kmer = kmer
+			copy(r.counts, counts)
+			r.present = true
+		}
+		return true
+	})
+	for i := maxsize; i >= minsize; i-- {
+		rows[i].flush(mins)
+		check(rows[i].w.Flush())
+		check(rows[i].file.Close())
+	}
+}
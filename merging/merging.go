@@ -3,11 +3,15 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime/debug"
 	"strconv"
 	"sync"
 
 	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+	"github.com/ericpauley/dna/kmerdb"
 	"github.com/ericpauley/go-hdf5"
 )
 
@@ -16,6 +20,12 @@ const readLimit = 10000
 var minAbundance uint = 3
 var minsize uint = 8
 var maxsize uint = 30
+var canonical bool
+var flat bool
+var matrix bool
+var sampleAbundances dna.CountList
+var mate int
+var poolMates bool
 
 func check(e error) {
 	if e != nil {
@@ -47,13 +57,7 @@ func kmerReader(table *hdf5.Table, reqs chan tableRead, size int, records int) c
 					result[i].Normalize(uint32(size))
 				}
 			}
-			tosend := result[:0]
-			for i := range result {
-				if result[i].Length >= uint32(minsize) {
-					result[i].Truncate(uint32(maxsize))
-					tosend = append(tosend, result[i])
-				}
-			}
+			tosend := filterKmers(result)
 			if len(tosend) > 0 {
 				c <- tosend
 			}
@@ -64,6 +68,46 @@ func kmerReader(table *hdf5.Table, reqs chan tableRead, size int, records int) c
 	return c
 }
 
+// filterKmers drops kmers outside the counted lengths and truncates the
+// rest to the max size, in place
+func filterKmers(result []dna.Kmer) []dna.Kmer {
+	tosend := result[:0]
+	for i := range result {
+		if result[i].Length >= uint32(minsize) {
+			if canonical && result[i].Length > uint32(maxsize) {
+				continue
+			}
+			result[i].Truncate(uint32(maxsize))
+			tosend = append(tosend, result[i])
+		}
+	}
+	return tosend
+}
+
+// dbReader streams the kmers of a Jellyfish or KMC database. With
+// -canonical they are brought into the canonical form used by
+// prefixcounting.
+func dbReader(name string) chan []dna.Kmer {
+	kmers, dbCanonical, err := kmerdb.Read(name)
+	check(err)
+	if dbCanonical && !canonical {
+		check(fmt.Errorf("%s holds canonical kmers, merge it with -canonical", name))
+	}
+	if canonical {
+		kmers = kmerdb.Canonicalize(kmers)
+	}
+	c := make(chan []dna.Kmer, 10)
+	go func() {
+		for batch := range kmerdb.Stream(kmers) {
+			if tosend := filterKmers(batch); len(tosend) > 0 {
+				c <- tosend
+			}
+		}
+		close(c)
+	}()
+	return c
+}
+
 func mergeStreams(first chan []dna.Kmer, second chan []dna.Kmer) chan []dna.Kmer {
 	c := make(chan []dna.Kmer, 1)
 	buf := make([]dna.Kmer, 0, readLimit)
@@ -115,12 +159,24 @@ func mergeStreams(first chan []dna.Kmer, second chan []dna.Kmer) chan []dna.Kmer
 				buf = make([]dna.Kmer, 0, readLimit)
 			}
 		}
+		if current.Count > 0 {
+			buf = append(buf, current)
+		}
 		c <- buf
 		close(c)
 	}()
 	return c
 }
 
+// mergeAll merges the sources into at most one stream
+func mergeAll(sources []chan []dna.Kmer) []chan []dna.Kmer {
+	sources = append([]chan []dna.Kmer(nil), sources...)
+	for len(sources) > 1 {
+		sources = append(sources[2:], mergeStreams(sources[0], sources[1]))
+	}
+	return sources
+}
+
 type tableRead struct {
 	table  *hdf5.Table
 	num    int
@@ -129,7 +185,7 @@ type tableRead struct {
 
 type tableWrite struct {
 	table  *hdf5.Table
-	data   []minimerCount
+	data   []counttable.Record
 	future chan error
 }
 
@@ -161,69 +217,137 @@ func streamKmers(reads chan tableRead, writes chan tableWrite, done chan bool) {
 	}
 }
 
-type minimerCount struct {
-	mmer  dna.Minimer
-	count uint32
+type output struct {
+	table    *hdf5.Table
+	file     *hdf5.File
+	current  counttable.Record
+	buffer   []counttable.Record
+	flat     *dna.FlatWriter
+	flatFile *os.File
 }
 
-type output struct {
-	table   *hdf5.Table
-	file    *hdf5.File
-	current minimerCount
-	buffer  []minimerCount
+// keep queues the current kmer of length l for writing
+func (o *output) keep(l uint32) {
+	o.buffer = append(o.buffer, o.current)
+	if o.flat != nil {
+		check(o.flat.Write(o.current.Kmer(int(l))))
+	}
 }
 
 func main() {
 	flag.UintVar(&minAbundance, "min-abundance", 1, "Min number of occurences to be solid")
 	flag.UintVar(&minsize, "min-size", 8, "Min kmer size to count")
 	flag.UintVar(&maxsize, "max-size", 30, "Max kmer size to count")
+	flag.BoolVar(&canonical, "canonical", false, "Inputs hold canonical kmers (prefixcounting -canonical)")
+	flag.BoolVar(&flat, "flat", false, "Also write memory mappable flat tables (merged{L}.kct)")
+	flag.BoolVar(&matrix, "matrix", false, "Write a kmer by sample count matrix per length (matrix{L}.tsv) in place of merged tables")
+	flag.IntVar(&mate, "mate", 0, "Merge only the kmers of read 1 or 2 of paired prefixcounting outputs, naming the outputs merged{L}.r1.h5 or merged{L}.r2.h5")
+	flag.BoolVar(&poolMates, "pool-mates", false, "Merge the kmers of both mates of paired prefixcounting outputs together")
+	flag.Var(&sampleAbundances, "sample-min-abundance", "With -matrix, a comma separated list of per input min abundances (default -min-abundance for all)")
 	flag.Parse()
+	if maxsize > uint(dna.MaxLength) {
+		fmt.Println("Error: max-size is limited to", dna.MaxLength, "in this build, use -tags kmer63 or kmer127 for longer kmers")
+		return
+	}
 	names := flag.Args()
 	if len(names) == 0 {
 		fmt.Println("Error: Must define an input file!")
 		return
 	}
+	if mate < 0 || mate > 2 {
+		fmt.Println("Error: -mate must be 0, 1 or 2")
+		return
+	}
+	if mate != 0 && poolMates {
+		fmt.Println("Error: -mate and -pool-mates cannot be combined")
+		return
+	}
+	if len(sampleAbundances) > 0 && len(sampleAbundances) != len(names) {
+		fmt.Println("Error: -sample-min-abundance needs one value per input")
+		return
+	}
 	lock.Lock()
 	var kmersources []chan []dna.Kmer
+	var filesources []chan []dna.Kmer
 	reads := make(chan tableRead)
 	writes := make(chan tableWrite, 2)
 	streamWait := make(chan bool)
 	for _, name := range names {
+		first := len(kmersources)
+		if filepath.Ext(name) != ".h5" {
+			kmersources = append(kmersources, dbReader(name))
+			if matrix {
+				filesources = append(filesources, kmersources[first])
+			}
+			continue
+		}
 		h5, err := hdf5.OpenFile(name, hdf5.F_ACC_RDONLY)
 		check(err)
-		group, err := h5.OpenGroup("dsk/solid")
 		var size int
-		if err != nil {
-			group, err = h5.OpenGroup("partials")
-			check(err)
-		} else {
+		var groups []*hdf5.Group
+		if group, err := h5.OpenGroup("dsk/solid"); err == nil {
+			groups = []*hdf5.Group{group}
 			size = 31
+		} else if groups, err = counttable.OpenPartials(h5, mate, poolMates); err != nil {
+			check(fmt.Errorf("%s %v", name, err))
 		}
-		num, err := group.NumObjects()
-		check(err)
-		for i := uint(0); i < num; i++ {
-			name, err := group.ObjectNameByIndex(i)
+		for _, group := range groups {
+			num, err := group.NumObjects()
 			check(err)
-			dset, _ := group.OpenTable(name)
-			defer dset.Close()
-			records, _ := dset.NumPackets()
-			kmersources = append(kmersources, kmerReader(dset, reads, size, records))
+			for i := uint(0); i < num; i++ {
+				name, err := group.ObjectNameByIndex(i)
+				check(err)
+				dset, _ := group.OpenTable(name)
+				defer dset.Close()
+				records, _ := dset.NumPackets()
+				kmersources = append(kmersources, kmerReader(dset, reads, size, records))
+			}
+		}
+		if matrix {
+			merged := mergeAll(kmersources[first:])
+			if len(merged) == 0 {
+				empty := make(chan []dna.Kmer)
+				close(empty)
+				merged = append(merged, empty)
+			}
+			filesources = append(filesources, merged[0])
 		}
 	}
-	for len(kmersources) > 1 {
-		kmersources = append(kmersources[2:], mergeStreams(kmersources[0], kmersources[1]))
+	if matrix {
+		mins := make([]uint32, len(names))
+		for i := range mins {
+			mins[i] = uint32(minAbundance)
+			if len(sampleAbundances) > 0 {
+				mins[i] = uint32(sampleAbundances[i])
+			}
+		}
+		lock.Unlock()
+		go streamKmers(reads, writes, streamWait)
+		writeMatrix(filesources, names, mins)
+		streamWait <- true
+		<-streamWait
+		return
 	}
+	kmersources = mergeAll(kmersources)
 	outputs := make([]output, maxsize+1)
 	for i := maxsize; i >= minsize; i-- {
-		h5, err := hdf5.CreateFile("merged"+strconv.Itoa(int(i))+".h5", hdf5.F_ACC_TRUNC)
+		h5, err := hdf5.CreateFile("merged"+strconv.Itoa(int(i))+dna.MateSuffix(mate)+".h5", hdf5.F_ACC_TRUNC)
 		check(err)
-		table, err := h5.CreateTableFrom("kmers", minimerCount{}, 1<<20, -1)
+		table, err := h5.CreateTableFrom("kmers", counttable.Record{}, 1<<20, -1)
 		check(err)
 		outputs[i].table = table
 		outputs[i].file = h5
-		outputs[i].buffer = make([]minimerCount, 0, readLimit)
+		outputs[i].buffer = make([]counttable.Record, 0, readLimit)
+		if flat {
+			f, err := os.Create("merged" + strconv.Itoa(int(i)) + dna.MateSuffix(mate) + ".kct")
+			check(err)
+			outputs[i].flatFile = f
+			outputs[i].flat, err = dna.NewFlatWriter(f, int(i))
+			check(err)
+		}
 	}
 	lock.Unlock()
+	hist := dna.NewHistogram()
 	println("Streaming kmers")
 	go streamKmers(reads, writes, streamWait)
 	i := 0
@@ -233,21 +357,30 @@ func main() {
 				i++
 				if i%1000000 == 0 {
 					debug.FreeOSMemory()
-					fmt.Println(i, kmer.ToRaw(), kmer.Length, kmer.Count)
+					fmt.Println(i, kmer, kmer.Count)
 				}
-				for l := kmer.Length; l >= uint32(minsize); l-- {
+				shortest := uint32(minsize)
+				if canonical {
+					// A truncated canonical kmer is not canonical itself, so
+					// canonical kmers only count towards their own length
+					shortest = kmer.Length
+				}
+				for l := kmer.Length; l >= shortest; l-- {
 					kmer.Truncate(l)
 					mmer := kmer.ToRaw()
-					if mmer.Cmp(outputs[l].current.mmer) == 0 {
-						outputs[l].current.count += kmer.Count
+					if mmer.Cmp(outputs[l].current.Mmer) == 0 {
+						outputs[l].current.Count += kmer.Count
 					} else {
-						if outputs[l].current.count >= uint32(minAbundance) {
-							outputs[l].buffer = append(outputs[l].buffer, outputs[l].current)
+						if outputs[l].current.Count > 0 {
+							hist.Add(l, outputs[l].current.Count)
+						}
+						if outputs[l].current.Count >= uint32(minAbundance) {
+							outputs[l].keep(l)
 						}
-						outputs[l].current = minimerCount{mmer, kmer.Count}
+						outputs[l].current = counttable.Record{Mmer: mmer, Count: kmer.Count}
 						if len(outputs[l].buffer) >= readLimit {
 							writes <- tableWrite{outputs[l].table, outputs[l].buffer, make(chan error)}
-							outputs[l].buffer = make([]minimerCount, 0, readLimit)
+							outputs[l].buffer = make([]counttable.Record, 0, readLimit)
 						}
 					}
 				}
@@ -256,6 +389,12 @@ func main() {
 	}
 	println("Pls no more reads")
 	for i := maxsize; i >= minsize; i-- {
+		if outputs[i].current.Count > 0 {
+			hist.Add(uint32(i), outputs[i].current.Count)
+			if outputs[i].current.Count >= uint32(minAbundance) {
+				outputs[i].keep(uint32(i))
+			}
+		}
 		if len(outputs[i].buffer) > 0 {
 			writes <- tableWrite{outputs[i].table, outputs[i].buffer, make(chan error)}
 		}
@@ -270,5 +409,15 @@ func main() {
 		outputs[i].file.Close()
 	}
 	lock.Unlock()
+	if flat {
+		for i := maxsize; i >= minsize; i-- {
+			check(outputs[i].flat.Close())
+			check(outputs[i].flatFile.Close())
+		}
+	}
+	hf, err := os.Create("merged" + dna.MateSuffix(mate) + ".hist.tsv")
+	check(err)
+	check(hist.WriteTSV(hf))
+	check(hf.Close())
 	println(i)
 }
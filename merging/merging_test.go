@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ericpauley/dna"
+)
+
+func stream(t *testing.T, seqs ...string) chan []dna.Kmer {
+	var batch []dna.Kmer
+	for _, s := range seqs {
+		kmer, err := dna.ParseKmer(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kmer.Count = 1
+		batch = append(batch, kmer)
+	}
+	c := make(chan []dna.Kmer, 1)
+	c <- batch
+	close(c)
+	return c
+}
+
+func TestMergeStreamsKeepsLastKmer(t *testing.T) {
+	merged := mergeStreams(stream(t, "AAAA", "CCCC", "GGGG"), stream(t, "CCCC", "TTTT"))
+	var got []string
+	var counts []uint32
+	for batch := range merged {
+		for _, kmer := range batch {
+			got = append(got, kmer.String())
+			counts = append(counts, kmer.Count)
+		}
+	}
+	// A<C<T<G in the 2-bit encoding, so GGGG is the last kmer
+	want := []string{"AAAA", "CCCC", "TTTT", "GGGG"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if counts[1] != 2 {
+		t.Errorf("CCCC counted %d times, want 2", counts[1])
+	}
+}
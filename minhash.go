@@ -0,0 +1,236 @@
+package dna
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// minHashMagic starts every serialized MinHash
+const minHashMagic = "SKMH"
+
+const minHashVersion = 1
+
+// Hash mixes the words of a Minimer into a 64 bit hash
+func (kmer Minimer) Hash() uint64 {
+	var h uint64
+	for _, word := range kmer {
+		h = Hashed(h ^ word)
+	}
+	return h
+}
+
+// hashHeap is a max-heap of hashes
+type hashHeap []uint64
+
+func (h hashHeap) Len() int            { return len(h) }
+func (h hashHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h hashHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hashHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *hashHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// MinHash is a bottom-s sketch of a kmer set, holding the Size smallest
+// hashes of its K base pair kmers
+type MinHash struct {
+	Name      string
+	K         int
+	Size      int
+	Canonical bool
+	heap      hashHeap
+	members   map[uint64]struct{}
+}
+
+// NewMinHash creates an empty sketch
+func NewMinHash(name string, k, size int, canonical bool) *MinHash {
+	return &MinHash{name, k, size, canonical, make(hashHeap, 0, size), make(map[uint64]struct{}, size)}
+}
+
+// Add adds a kmer, which must be K base pairs long, to the sketch
+func (m *MinHash) Add(kmer Kmer) {
+	if m.Canonical {
+		kmer = kmer.Canonical()
+	}
+	m.addHash(kmer.ToMini().Hash())
+}
+
+func (m *MinHash) addHash(h uint64) {
+	if _, ok := m.members[h]; ok {
+		return
+	}
+	if len(m.heap) < m.Size {
+		heap.Push(&m.heap, h)
+		m.members[h] = struct{}{}
+	} else if h < m.heap[0] {
+		delete(m.members, m.heap[0])
+		m.heap[0] = h
+		heap.Fix(&m.heap, 0)
+		m.members[h] = struct{}{}
+	}
+}
+
+// AddSequence adds every kmer of a sequence
+func (m *MinHash) AddSequence(seq []byte) {
+	ScanOptions{Mode: AllPositions, Min: m.K, Max: m.K}.Scan(seq, func(kmer Kmer) bool {
+		m.Add(kmer)
+		return true
+	})
+}
+
+// Hashes returns the sketch hashes in increasing order
+func (m *MinHash) Hashes() []uint64 {
+	hashes := append([]uint64(nil), m.heap...)
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
+
+// threshold returns the largest hash a full sketch keeps
+func (m *MinHash) threshold() uint64 {
+	if len(m.heap) < m.Size {
+		return math.MaxUint64
+	}
+	return m.heap[0]
+}
+
+// compare counts the hashes both sketches would hold, the shared ones and
+// the ones only in m
+func (m *MinHash) compare(o *MinHash) (shared, mine, union int, err error) {
+	if m.K != o.K || m.Canonical != o.Canonical {
+		return 0, 0, 0, fmt.Errorf("dna: cannot compare sketches of %s and %s with different kmers", m.Name, o.Name)
+	}
+	t := m.threshold()
+	if ot := o.threshold(); ot < t {
+		t = ot
+	}
+	for h := range m.members {
+		if h > t {
+			continue
+		}
+		mine++
+		union++
+		if _, ok := o.members[h]; ok {
+			shared++
+		}
+	}
+	for h := range o.members {
+		if _, ok := m.members[h]; !ok && h <= t {
+			union++
+		}
+	}
+	return shared, mine, union, nil
+}
+
+// Jaccard estimates the Jaccard index of the two kmer sets
+func (m *MinHash) Jaccard(o *MinHash) (float64, error) {
+	shared, _, union, err := m.compare(o)
+	if err != nil || union == 0 {
+		return 0, err
+	}
+	return float64(shared) / float64(union), nil
+}
+
+// Containment estimates the fraction of the kmers of m also in o
+func (m *MinHash) Containment(o *MinHash) (float64, error) {
+	shared, mine, _, err := m.compare(o)
+	if err != nil || mine == 0 {
+		return 0, err
+	}
+	return float64(shared) / float64(mine), nil
+}
+
+// MashDistance estimates the per base mutation distance between the two
+// sequences from their Jaccard index
+func (m *MinHash) MashDistance(o *MinHash) (float64, error) {
+	j, err := m.Jaccard(o)
+	if err != nil {
+		return 0, err
+	}
+	if j == 0 {
+		return 1, nil
+	}
+	return -math.Log(2*j/(1+j)) / float64(m.K), nil
+}
+
+// WriteTo serializes the sketch
+func (m *MinHash) WriteTo(w io.Writer) (int64, error) {
+	hashes := m.Hashes()
+	buf := make([]byte, 0, 24+len(m.Name)+8*len(hashes))
+	buf = append(buf, minHashMagic...)
+	var canonical uint32
+	if m.Canonical {
+		canonical = 1
+	}
+	for _, v := range []uint32{minHashVersion, uint32(m.K), uint32(m.Size), canonical, uint32(len(m.Name))} {
+		buf = binary.LittleEndian.AppendUint32(buf, v)
+	}
+	buf = append(buf, m.Name...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(hashes)))
+	for _, h := range hashes {
+		buf = binary.LittleEndian.AppendUint64(buf, h)
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// ReadMinHash reads a sketch written by WriteTo. Memory grows with the data
+// actually read, so a corrupt header cannot cause a huge allocation.
+func ReadMinHash(r io.Reader) (*MinHash, error) {
+	var head [24]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	if string(head[:4]) != minHashMagic {
+		return nil, errors.New("dna: not a MinHash sketch")
+	}
+	if v := binary.LittleEndian.Uint32(head[4:]); v != minHashVersion {
+		return nil, fmt.Errorf("dna: unsupported MinHash version %d", v)
+	}
+	k := int(binary.LittleEndian.Uint32(head[8:]))
+	size := int(binary.LittleEndian.Uint32(head[12:]))
+	canonical := binary.LittleEndian.Uint32(head[16:]) != 0
+	var name bytes.Buffer
+	if _, err := io.CopyN(&name, r, int64(binary.LittleEndian.Uint32(head[20:]))); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	var count [4]byte
+	if _, err := io.ReadFull(r, count[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	n := int(binary.LittleEndian.Uint32(count[:]))
+	if n > size {
+		return nil, fmt.Errorf("dna: MinHash sketch of size %d holds %d hashes", size, n)
+	}
+	m := &MinHash{Name: name.String(), K: k, Size: size, Canonical: canonical, members: map[uint64]struct{}{}}
+	var hashes [8 * 1024]byte
+	for n > 0 {
+		batch := hashes[:]
+		if 8*n < len(batch) {
+			batch = batch[:8*n]
+		}
+		if _, err := io.ReadFull(r, batch); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		for i := 0; i < len(batch); i += 8 {
+			m.addHash(binary.LittleEndian.Uint64(batch[i:]))
+		}
+		n -= len(batch) / 8
+	}
+	return m, nil
+}
+
+// unexpectedEOF reports a sketch ending early as io.ErrUnexpectedEOF
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package dna
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestMinHashRoundTrip(t *testing.T) {
+	m := NewMinHash("sample", 5, 3000, true)
+	for i := 0; i < 5000; i++ {
+		m.addHash(Hashed(uint64(i)))
+	}
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadMinHash(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != m.Name || got.K != m.K || got.Size != m.Size || got.Canonical != m.Canonical {
+		t.Fatalf("got %+v, want %+v", got, m)
+	}
+	a, b := got.Hashes(), m.Hashes()
+	if len(a) != len(b) {
+		t.Fatalf("got %d hashes, want %d", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("hash %d: got %x, want %x", i, a[i], b[i])
+		}
+	}
+}
+
+func TestReadMinHashCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewMinHash("x", 5, 10, false).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	good := buf.Bytes()
+	// A hash count beyond the sketch size
+	overfull := append([]byte(nil), good...)
+	binary.LittleEndian.PutUint32(overfull[len(overfull)-4:], 11)
+	// Huge name and hash counts in a short file must fail without
+	// allocating for them
+	longName := append([]byte(nil), good...)
+	binary.LittleEndian.PutUint32(longName[20:], 1<<31)
+	huge := append([]byte(nil), good...)
+	binary.LittleEndian.PutUint32(huge[12:], 1<<31)
+	binary.LittleEndian.PutUint32(huge[len(huge)-4:], 1<<31)
+	for name, b := range map[string][]byte{"overfull": overfull, "long name": longName, "huge": huge} {
+		if _, err := ReadMinHash(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: read a corrupt sketch", name)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package dna
+
+import "fmt"
+
+// Order ranks the packed value of a kmer of at most 32 base pairs. The lowest
+// ranked kmer of a window is its minimizer.
+type Order func(value uint64) uint64
+
+// Lexicographic ranks kmers by their encoding, so A < C < T < G
+func Lexicographic(value uint64) uint64 {
+	return value
+}
+
+// Hashed ranks kmers by a 64 bit mix of their encoding, which avoids the
+// lexicographic bias towards runs of A
+func Hashed(value uint64) uint64 {
+	value ^= value >> 33
+	value *= 0xff51afd7ed558ccd
+	value ^= value >> 33
+	value *= 0xc4ceb9fe1a85ec53
+	value ^= value >> 33
+	return value
+}
+
+// Minimizer is a kmer sampled from a sequence
+type Minimizer struct {
+	// Pos is the offset of the kmer in the sequence
+	Pos int
+	// Value holds the kmer LSB aligned, two bits per base pair
+	Value uint64
+}
+
+// Kmer converts the sampled value of a k base pair kmer into a Kmer
+func (m Minimizer) Kmer(k int) Kmer {
+	var kmer Kmer
+	kmer.Kmer[kmerwords-1] = m.Value
+	kmer.Normalize(uint32(k))
+	kmer.Count = 1
+	return kmer
+}
+
+func checkSampling(k, w int) {
+	if k < 1 || k > 32 || w < 1 {
+		panic(fmt.Sprintf("dna: invalid sampling parameters k=%d w=%d", k, w))
+	}
+}
+
+// windows slides a window of w consecutive m base pair kmers over seq and
+// calls tocall with the offset of each window and its lowest ranked kmer.
+// Windows holding a non-ACGT byte are skipped, ties go to the leftmost kmer.
+func windows(seq []byte, m, w int, order Order, tocall func(start int, min Minimizer) bool) bool {
+	type ranked struct {
+		Minimizer
+		rank uint64
+	}
+	mask := ^uint64(0) >> uint(64-2*m)
+	queue := make([]ranked, 0, w)
+	var value uint64
+	valid := 0
+	for i, b := range seq {
+		bp, ok := EncodeBase(b)
+		if !ok {
+			valid = 0
+			queue = queue[:0]
+			continue
+		}
+		value = (value<<2 | bp) & mask
+		valid++
+		if valid < m {
+			continue
+		}
+		pos := i - m + 1
+		next := ranked{Minimizer{pos, value}, order(value)}
+		for len(queue) > 0 && queue[len(queue)-1].rank > next.rank {
+			queue = queue[:len(queue)-1]
+		}
+		queue = append(queue, next)
+		start := pos - w + 1
+		if queue[0].Pos < start {
+			queue = queue[1:]
+		}
+		if valid-m+1 >= w && !tocall(start, queue[0].Minimizer) {
+			return false
+		}
+	}
+	return true
+}
+
+// Minimizers calls tocall with the (w,k)-minimizer of every window of w
+// consecutive kmers in seq, reporting a minimizer shared by consecutive
+// windows once. It returns false if tocall stopped the iteration.
+func Minimizers(seq []byte, k, w int, order Order, tocall func(Minimizer) bool) bool {
+	checkSampling(k, w)
+	last := -1
+	return windows(seq, k, w, order, func(start int, min Minimizer) bool {
+		if min.Pos == last {
+			return true
+		}
+		last = min.Pos
+		return tocall(min)
+	})
+}
+
+// Syncmers calls tocall with every kmer of seq whose lowest ranked s base pair
+// substring starts the kmer (open syncmers) or, when closed is set, either
+// starts or ends it (closed syncmers). It returns false if tocall stopped the
+// iteration.
+func Syncmers(seq []byte, k, s int, closed bool, order Order, tocall func(Minimizer) bool) bool {
+	checkSampling(k, k-s+1)
+	checkSampling(s, 1)
+	return windows(seq, s, k-s+1, order, func(start int, min Minimizer) bool {
+		if min.Pos != start && !(closed && min.Pos == start+k-s) {
+			return true
+		}
+		var value uint64
+		for _, b := range seq[start : start+k] {
+			bp, _ := EncodeBase(b)
+			value = value<<2 | bp
+		}
+		return tocall(Minimizer{start, value})
+	})
+}
+
+// Partitioner assigns kmers to sectors by the minimizer of their last span
+// base pairs, so every kmer cut down from the same read end lands in the
+// same sector as long as it keeps those span base pairs.
+type Partitioner struct {
+	span  int
+	k     int
+	mask  uint64
+	order Order
+}
+
+// NewPartitioner creates a Partitioner over a power of two number of sectors
+// using k base pair minimizers ranked by order
+func NewPartitioner(span, k, sectors int, order Order) *Partitioner {
+	checkSampling(k, span-k+1)
+	if sectors < 1 || sectors&(sectors-1) != 0 {
+		panic(fmt.Sprintf("dna: sector count %d is not a power of two", sectors))
+	}
+	return &Partitioner{span, k, uint64(sectors - 1), order}
+}
+
+// Sector returns the sector of the kmer
+func (p *Partitioner) Sector(kmer Kmer) int {
+	start := int(kmer.Length) - p.span
+	if start < 0 {
+		start = 0
+	}
+	mask := ^uint64(0) >> uint(64-2*p.k)
+	var value, min, minRank uint64
+	found := false
+	for i := start; i < int(kmer.Length); i++ {
+		value = (value<<2 | kmer.Kmer[i/32]>>uint(62-2*(i%32))&3) & mask
+		if i-start+1 >= p.k {
+			if rank := p.order(value); !found || rank < minRank {
+				min, minRank, found = value, rank, true
+			}
+		}
+	}
+	if !found {
+		// Kmers shorter than k hash as a whole
+		min = value
+	}
+	return int(Hashed(min) & p.mask)
+}
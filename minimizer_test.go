@@ -0,0 +1,154 @@
+package dna
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomSeq returns n random base pairs with roughly one in nN an N, none
+// if nN is 0
+func randomSeq(r *rand.Rand, n, nN int) []byte {
+	seq := make([]byte, n)
+	for i := range seq {
+		seq[i] = "ACGT"[r.Intn(4)]
+		if nN > 0 && r.Intn(nN) == 0 {
+			seq[i] = 'N'
+		}
+	}
+	return seq
+}
+
+// encode returns the LSB aligned value of seq, or false if it holds a
+// non-ACGT byte
+func encode(seq []byte) (uint64, bool) {
+	var value uint64
+	for _, b := range seq {
+		bp, ok := EncodeBase(b)
+		if !ok {
+			return 0, false
+		}
+		value = value<<2 | bp
+	}
+	return value, true
+}
+
+// lowest returns the offset of the leftmost lowest ranked m base pair kmer
+// of seq
+func lowest(seq []byte, m int, order Order) int {
+	best := -1
+	var bestRank uint64
+	for i := 0; i+m <= len(seq); i++ {
+		value, _ := encode(seq[i : i+m])
+		if rank := order(value); best < 0 || rank < bestRank {
+			best, bestRank = i, rank
+		}
+	}
+	return best
+}
+
+func bruteMinimizers(seq []byte, k, w int, order Order) []Minimizer {
+	var out []Minimizer
+	span := k + w - 1
+	for start := 0; start+span <= len(seq); start++ {
+		if _, ok := encode(seq[start : start+span]); !ok {
+			continue
+		}
+		pos := start + lowest(seq[start:start+span], k, order)
+		if len(out) > 0 && out[len(out)-1].Pos == pos {
+			continue
+		}
+		value, _ := encode(seq[pos : pos+k])
+		out = append(out, Minimizer{pos, value})
+	}
+	return out
+}
+
+func bruteSyncmers(seq []byte, k, s int, closed bool, order Order) []Minimizer {
+	var out []Minimizer
+	for start := 0; start+k <= len(seq); start++ {
+		value, ok := encode(seq[start : start+k])
+		if !ok {
+			continue
+		}
+		pos := lowest(seq[start:start+k], s, order)
+		if pos == 0 || (closed && pos == k-s) {
+			out = append(out, Minimizer{start, value})
+		}
+	}
+	return out
+}
+
+func collect(sample func(func(Minimizer) bool) bool) []Minimizer {
+	var out []Minimizer
+	sample(func(m Minimizer) bool {
+		out = append(out, m)
+		return true
+	})
+	return out
+}
+
+var orders = map[string]Order{"lexicographic": Lexicographic, "hashed": Hashed}
+
+func TestMinimizers(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for name, order := range orders {
+		for _, nN := range []int{0, 40} {
+			for _, p := range [][2]int{{1, 1}, {3, 1}, {5, 4}, {11, 10}, {21, 11}, {32, 3}} {
+				k, w := p[0], p[1]
+				seq := randomSeq(r, 500, nN)
+				got := collect(func(f func(Minimizer) bool) bool { return Minimizers(seq, k, w, order, f) })
+				if want := bruteMinimizers(seq, k, w, order); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s k=%d w=%d N=%v: got %d minimizers, want %d", name, k, w, nN > 0, len(got), len(want))
+				}
+			}
+		}
+	}
+}
+
+func TestSyncmers(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for name, order := range orders {
+		for _, nN := range []int{0, 40} {
+			for _, closed := range []bool{false, true} {
+				for _, p := range [][2]int{{5, 2}, {15, 5}, {21, 11}, {31, 15}, {8, 8}} {
+					k, s := p[0], p[1]
+					seq := randomSeq(r, 500, nN)
+					got := collect(func(f func(Minimizer) bool) bool { return Syncmers(seq, k, s, closed, order, f) })
+					if want := bruteSyncmers(seq, k, s, closed, order); !reflect.DeepEqual(got, want) {
+						t.Errorf("%s k=%d s=%d closed=%v N=%v: got %d syncmers, want %d", name, k, s, closed, nN > 0, len(got), len(want))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMinimizersStop(t *testing.T) {
+	seq := randomSeq(rand.New(rand.NewSource(3)), 100, 0)
+	calls := 0
+	if Minimizers(seq, 5, 4, Hashed, func(Minimizer) bool { calls++; return false }) || calls != 1 {
+		t.Errorf("Minimizers kept going after tocall returned false")
+	}
+}
+
+func TestPartitionerCut(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for _, p := range [][2]int{{8, 5}, {11, 11}, {20, 9}} {
+		span, k := p[0], p[1]
+		part := NewPartitioner(span, k, 64, Hashed)
+		for n := 0; n < 200; n++ {
+			var kmer Kmer
+			for l := MaxLength; l > 0; l-- {
+				kmer.Push(uint64(r.Intn(4)))
+			}
+			want := part.Sector(kmer)
+			for kmer.Length > uint32(span) {
+				kmer.Cut()
+				if got := part.Sector(kmer); got != want {
+					t.Fatalf("span=%d k=%d: sector %d at length %d, %d at %d", span, k, got, kmer.Length, want, MaxLength)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package dna
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the whole file where memory mapping is not available
+func mapFile(f *os.File) ([]byte, func() error, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
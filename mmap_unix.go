@@ -0,0 +1,25 @@
+//go:build unix
+
+package dna
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the whole file read-only. Empty files, which cannot be
+// mapped, give an empty slice.
+func mapFile(f *os.File) ([]byte, func() error, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if fi.Size() == 0 {
+		return []byte{}, func() error { return nil }, nil
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
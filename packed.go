@@ -0,0 +1,106 @@
+package dna
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// packedMaxLength is the most base pairs a packed record holds beside its
+// marker
+const packedMaxLength = 31
+
+// packedEmpty is a packed record holding no base pairs: the marker alone
+const packedEmpty = 1 << 62
+
+// PackedWriter writes sequences as a stream of LayoutPacked records: single
+// little endian words the base pairs are shifted into from the top, so the
+// newest sits in the high bits and a marker bit follows the oldest. Runs
+// longer than 31 base pairs are split over several records.
+type PackedWriter struct {
+	w      *bufio.Writer
+	block  uint64
+	pushed int
+	buf    [8]byte
+}
+
+// NewPackedWriter starts a packed stream on w
+func NewPackedWriter(w io.Writer) (*PackedWriter, error) {
+	h := NewHeader(LayoutPacked)
+	h.KmerWords = 1
+	h.MinLength = 1
+	h.MaxLength = packedMaxLength
+	p := &PackedWriter{w: bufio.NewWriterSize(w, 1<<20), block: packedEmpty}
+	return p, h.Write(p.w)
+}
+
+// Push appends a base pair code to the current run
+func (p *PackedWriter) Push(bp uint64) error {
+	p.block = p.block>>2 | bp<<62
+	p.pushed++
+	if p.pushed == packedMaxLength {
+		return p.End()
+	}
+	return nil
+}
+
+// End writes the current run, if any, as a record
+func (p *PackedWriter) End() error {
+	if p.pushed == 0 {
+		return nil
+	}
+	binary.LittleEndian.PutUint64(p.buf[:], p.block)
+	p.block, p.pushed = packedEmpty, 0
+	_, err := p.w.Write(p.buf[:])
+	return err
+}
+
+// Flush ends the current run and flushes the stream
+func (p *PackedWriter) Flush() error {
+	if err := p.End(); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// PackedReader reads the runs of a stream written by PackedWriter
+type PackedReader struct {
+	r   *bufio.Reader
+	buf [8]byte
+}
+
+// NewPackedReader reads the header of a packed stream and checks it
+func NewPackedReader(r io.Reader) (*PackedReader, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	h, err := ReadHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.Check(LayoutPacked); err != nil {
+		return nil, err
+	}
+	return &PackedReader{r: br}, nil
+}
+
+// Next returns the next run as a kmer with its oldest base pair first, or
+// io.EOF at the end of the stream
+func (p *PackedReader) Next() (Kmer, error) {
+	if _, err := io.ReadFull(p.r, p.buf[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("dna: packed stream ends inside a record")
+		}
+		return Kmer{}, err
+	}
+	block := binary.LittleEndian.Uint64(p.buf[:])
+	marker := bits.TrailingZeros64(block)
+	if marker%2 != 0 || marker > 60 {
+		return Kmer{}, fmt.Errorf("dna: packed record %#x has no marker", block)
+	}
+	var kmer Kmer
+	for i := 0; i < (62-marker)/2; i++ {
+		kmer.Push(block >> (62 - 2*uint(i)) & 3)
+	}
+	return kmer, nil
+}
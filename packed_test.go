@@ -0,0 +1,67 @@
+package dna
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPackedRoundTrip(t *testing.T) {
+	long := strings.Repeat("ACGT", 10)
+	runs := []string{"A", "GATTACA", long[:31], long[:35], "T"}
+	var buf bytes.Buffer
+	w, err := NewPackedWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, run := range runs {
+		for _, b := range []byte(run) {
+			bp, _ := EncodeBase(b)
+			if err := w.Push(bp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.End(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Ending an empty run writes nothing
+	if err := w.End(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewPackedReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		kmer, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, kmer.String())
+	}
+	want := []string{"A", "GATTACA", long[:31], long[:31], long[31:35], "T"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPackedReaderRejectsOtherStreams(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHeader(LayoutMinimer)
+	h.MinLength, h.MaxLength = 1, 21
+	if err := h.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPackedReader(&buf); err == nil {
+		t.Error("minimer stream read as packed")
+	}
+}
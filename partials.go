@@ -0,0 +1,51 @@
+package dna
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PartialsGroup is the group of a prefixcounting output holding the sector
+// tables of single end reads. Paired reads get a group per mate instead,
+// see MateGroup.
+const PartialsGroup = "partials"
+
+// MateGroup returns the group holding the sector tables of mate 1 or 2
+func MateGroup(mate int) string {
+	return PartialsGroup + "_r" + strconv.Itoa(mate)
+}
+
+// MateSuffix returns the part of an output name telling which mate it
+// holds: ".r1" or ".r2", or nothing for 0
+func MateSuffix(mate int) string {
+	if mate == 0 {
+		return ""
+	}
+	return ".r" + strconv.Itoa(mate)
+}
+
+// PartialGroups returns the groups of a prefixcounting output to read for
+// mate 1 or 2 of paired reads, or 0 for single end reads. With pool, mate 0
+// reads both mates of paired reads together. exists reports whether the
+// output holds a group.
+func PartialGroups(mate int, pool bool, exists func(group string) bool) ([]string, error) {
+	if exists(PartialsGroup) {
+		if mate != 0 {
+			return nil, fmt.Errorf("holds single end reads, which have no mate %d", mate)
+		}
+		return []string{PartialsGroup}, nil
+	}
+	var groups []string
+	for m := 1; m <= 2; m++ {
+		if (mate == 0 || mate == m) && exists(MateGroup(m)) {
+			groups = append(groups, MateGroup(m))
+		}
+	}
+	switch {
+	case len(groups) == 0:
+		return nil, fmt.Errorf("holds no partials for mate %d", mate)
+	case mate == 0 && !pool:
+		return nil, fmt.Errorf("holds paired reads, pick mate 1 or 2 or pool both")
+	}
+	return groups, nil
+}
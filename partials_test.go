@@ -0,0 +1,33 @@
+package dna
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPartialGroups(t *testing.T) {
+	single := func(g string) bool { return g == "partials" }
+	paired := func(g string) bool { return g == "partials_r1" || g == "partials_r2" }
+	none := func(string) bool { return false }
+	tests := []struct {
+		name   string
+		mate   int
+		pool   bool
+		exists func(string) bool
+		want   []string
+	}{
+		{"single end", 0, false, single, []string{"partials"}},
+		{"single end mate", 1, false, single, nil},
+		{"mate 1", 1, false, paired, []string{"partials_r1"}},
+		{"mate 2", 2, true, paired, []string{"partials_r2"}},
+		{"pooled", 0, true, paired, []string{"partials_r1", "partials_r2"}},
+		{"unpooled", 0, false, paired, nil},
+		{"empty", 0, true, none, nil},
+	}
+	for _, tt := range tests {
+		got, err := PartialGroups(tt.mate, tt.pool, tt.exists)
+		if (err == nil) != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
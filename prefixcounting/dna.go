@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"runtime"
@@ -26,13 +27,31 @@ func check(e error) {
 
 const sorters = 1
 
+// partitionK is the minimizer size used to assign kmers to sectors
+const partitionK = 11
+
 var maxmem uint = 2048 * 1024 * 1024
 var maxCores uint
 var minAbundance = 1
-var counts countList
+var counts dna.CountList
 var minsize = 8
 var maxsize = 30
 var maxrecords uint
+var canonical bool
+var prefilter bool
+var prefilterMem uint
+var minQual int
+var qualMode string
+var qualOffset int
+
+var qualModes = map[string]dna.QualPolicy{
+	"truncate": dna.TruncateAtQual,
+	"skip":     dna.SkipAtQual,
+	"kmer":     dna.KmerQual,
+}
+
+// sketchDepth is the number of rows in the prefilter count-min sketch
+const sketchDepth = 4
 
 type sector struct {
 	tmpfile *os.File
@@ -42,42 +61,12 @@ type sector struct {
 	toSort  chan dna.Mmerlist
 }
 
-type countList []uint
-
-func (i *countList) String() string {
-	return fmt.Sprint(*i)
-}
-
-func (i *countList) Set(value string) error {
-	// If we wanted to allow the flag to be set multiple times,
-	// accumulating values, we would delete this if statement.
-	// That would permit usages such as
-	//	-deltaT 10s -deltaT 15s
-	// and other combinations.
-	for _, dt := range strings.Split(value, ",") {
-		count, err := strconv.Atoi(dt)
-		if err != nil {
-			return err
-		}
-		*i = append(*i, uint(count))
-	}
-	return nil
-}
-
-func (i *countList) Max() uint {
-	var max uint
-	for _, j := range *i {
-		if j > max {
-			max = j
-		}
-	}
-	return max
-}
-
-func calcSectors(f *os.File) ([]*sector, int) {
+// calcSectors plans the sectors of every mate group over the combined
+// inputs. Sector i of group g is at g*len/groups+i.
+func calcSectors(inputs []input, groups int) []*sector {
 	println("Calculating sectors")
 	checked := 0
-	scanned := scan(f, func(kmer dna.Kmer) bool {
+	scanned, _ := scan(inputs, func(_ int, kmer dna.Kmer) bool {
 		checked++
 		if checked > 1024 {
 			return false
@@ -87,29 +76,86 @@ func calcSectors(f *os.File) ([]*sector, int) {
 	total := int(float64(maxsize-minsize+1) * float64(checked) / scanned)
 	sectors := 4
 	println(total / sectors)
-	sectorbits := 2
 	for uint(total/sectors) > maxrecords {
 		sectors <<= 1
-		sectorbits++
 	}
-	sectorSlice := make([]*sector, sectors)
-	for i := 0; i < sectors; i++ {
+	sectorSlice := make([]*sector, sectors*groups)
+	for i := range sectorSlice {
 		sectorSlice[i] = &sector{nil, make(chan dna.Kmer, 100), 0, make(chan dna.Mmerlist), make(chan dna.Mmerlist)}
 	}
-	return sectorSlice, sectorbits
+	return sectorSlice
 }
 
-func saveChunks(oname string, counts countList, sectors []*sector, sjoin *sync.WaitGroup) {
+// counted returns the form a kmer is counted under
+func counted(kmer dna.Kmer) dna.Minimer {
+	if canonical {
+		return kmer.Canonical().ToMini()
+	}
+	return kmer.ToMini()
+}
+
+// buildSketch estimates the count of every length cut from the scanned kmers
+func buildSketch(inputs []input) *dna.CountMinSketch {
+	println("Building prefilter sketch")
+	sketch := dna.NewCountMinSketch(int(prefilterMem*1024*1024/2/sketchDepth), sketchDepth)
+	scan(inputs, func(_ int, kmer dna.Kmer) bool {
+		for ; kmer.Length >= uint32(minsize); kmer.Cut() {
+			sketch.Add(counted(kmer))
+		}
+		return true
+	}, minsize, maxsize, true)
+	return sketch
+}
+
+// solid cuts the kmer down to its longest length that may reach
+// minAbundance. Longer lengths are estimated below it by the sketch and so
+// cannot be solid.
+func solid(kmer dna.Kmer, sketch *dna.CountMinSketch) (dna.Kmer, bool) {
+	for ; kmer.Length >= uint32(minsize); kmer.Cut() {
+		if sketch.Estimate(counted(kmer)) >= uint32(minAbundance) {
+			return kmer, true
+		}
+	}
+	return kmer, false
+}
+
+// mateGroups returns the partials groups of single end or paired reads
+func mateGroups(paired bool) []string {
+	if paired {
+		return []string{dna.MateGroup(1), dna.MateGroup(2)}
+	}
+	return []string{dna.PartialsGroup}
+}
+
+func saveChunks(oname string, counts dna.CountList, sectors []*sector, groupNames []string, inputs []input, sjoin *sync.WaitGroup) {
 	h5, err := hdf5.CreateFile(oname, hdf5.F_ACC_TRUNC)
 	check(err)
-	group, _ := h5.CreateGroup("partials")
+	groups := make([]*hdf5.Group, len(groupNames))
+	for i, name := range groupNames {
+		groups[i], err = h5.CreateGroup(name)
+		check(err)
+	}
+	if canonical {
+		// A kmer and its reverse complement may land in different sectors,
+		// so sector counts are partial and only merging can total them
+		marker, err := h5.CreateGroup("canonical")
+		check(err)
+		marker.Close()
+	}
+	perGroup := len(sectors) / len(groups)
+	// Paired reads get a histogram per mate, like their partials
+	hists := make([]*dna.Histogram, len(groups))
+	for i := range hists {
+		hists[i] = dna.NewHistogram()
+	}
 	println("Ready to save sectors")
 	start := time.Now()
 	for snum, sector := range sectors {
 		kmers := <-sector.sorted
 		fmt.Println("Received sector", len(kmers), time.Now().Sub(start))
 		ostart := time.Now()
-		table, err := group.CreateTableFrom(strconv.Itoa(snum), dna.Kmer{}, 1<<20, -1)
+		hist := hists[snum/perGroup]
+		table, err := groups[snum/perGroup].CreateTableFrom(strconv.Itoa(snum%perGroup), dna.Kmer{}, 1<<20, -1)
 		check(err)
 		todump := make(dna.Kmerlist, 0, 10000)
 		var current dna.Kmer
@@ -118,7 +164,10 @@ func saveChunks(oname string, counts countList, sectors []*sector, sjoin *sync.W
 			if current.Cmp(kmer) == 0 {
 				current.Count++
 			} else {
-				if current.Length >= uint32(minAbundance) {
+				if current.Count > 0 {
+					hist.Add(current.Length, current.Count)
+				}
+				if current.Count > 0 && current.Count >= uint32(minAbundance) {
 					todump = append(todump, current)
 				}
 				current = kmer
@@ -128,6 +177,12 @@ func saveChunks(oname string, counts countList, sectors []*sector, sjoin *sync.W
 				todump = todump[:0]
 			}
 		}
+		if current.Count > 0 {
+			hist.Add(current.Length, current.Count)
+		}
+		if current.Count > 0 && current.Count >= uint32(minAbundance) {
+			todump = append(todump, current)
+		}
 		kmers = nil
 		debug.FreeOSMemory()
 		table.Append(&todump)
@@ -136,22 +191,45 @@ func saveChunks(oname string, counts countList, sectors []*sector, sjoin *sync.W
 		fmt.Println("Saved", time.Now().Sub(ostart))
 		start = time.Now()
 	}
+	saveInputs(h5, inputs)
 	h5.Flush(hdf5.F_SCOPE_GLOBAL)
 	h5.Close()
+	if !canonical {
+		for i, hist := range hists {
+			mate := 0
+			if len(hists) > 1 {
+				mate = i + 1
+			}
+			hf, err := os.Create(strings.TrimSuffix(oname, ".h5") + dna.MateSuffix(mate) + ".hist.tsv")
+			check(err)
+			check(hist.WriteTSV(hf))
+			check(hf.Close())
+		}
+	}
 	sjoin.Done()
 }
 
+// sectorHeader returns the header of the sector files, Records and Checksum
+// are filled in once the scan is done
+func sectorHeader() dna.Header {
+	h := dna.NewHeader(dna.LayoutMinimer)
+	h.MinLength = uint32(minsize)
+	h.MaxLength = uint32(maxsize)
+	return h
+}
+
 func writeChunk(s *sector, pjoin *sync.WaitGroup) {
 	t, err := ioutil.TempFile("", "kmer")
 	check(err)
 	s.tmpfile = t
 	bufd := bufio.NewWriterSize(t, 4*1024*1024)
-	check(err)
+	enc := dna.NewEncoder(bufd)
+	check(enc.WriteHeader(sectorHeader()))
 	for kmer := range s.c {
 		s.len++
-		kmer.Write(bufd)
+		check(enc.EncodeKmer(kmer))
 	}
-	bufd.Flush()
+	check(bufd.Flush())
 	pjoin.Done()
 }
 
@@ -161,7 +239,7 @@ func processChunks(c chan *sector, ojoin *sync.WaitGroup) {
 	for sector := range c {
 		kmers := <-sector.toSort
 		fmt.Println("Sorting chunk!", time.Now().Sub(start))
-		kmers.Sort(0)
+		kmers.RadixSort(int(maxCores))
 		sector.sorted <- kmers
 		kmers = nil
 		debug.FreeOSMemory()
@@ -179,14 +257,58 @@ func main() {
 	flag.IntVar(&minAbundance, "min-abundance", 1, "Min number of occurences to be solid")
 	flag.IntVar(&minsize, "min-size", 8, "Min kmer size to count")
 	flag.IntVar(&maxsize, "max-size", 30, "Max kmer size to count")
+	flag.BoolVar(&canonical, "canonical", false, "Count kmers and their reverse complements together")
+	flag.BoolVar(&prefilter, "prefilter", false, "Drop kmers below min-abundance with a count-min sketch pass before sectoring")
+	flag.UintVar(&prefilterMem, "prefilter-mem", 512, "Memory for the prefilter sketch (MB)")
+	flag.IntVar(&minQual, "min-qual", 0, "Min Phred score of FASTQ base pairs (0 to ignore qualities)")
+	flag.StringVar(&qualMode, "qual-mode", "truncate", "How reads are cut at low quality base pairs: truncate (at the first one), skip (the read) or kmer (check every kmer)")
+	flag.IntVar(&qualOffset, "qual-offset", 33, "The ASCII offset of FASTQ quality strings")
+	var mate2 string
+	var interleaved bool
+	flag.StringVar(&mate2, "r2", "", "The read 2 files of paired reads (a file, directory or pattern), paired in order with the inputs")
+	flag.BoolVar(&interleaved, "interleaved", false, "The input holds paired reads, read 1 and read 2 alternating")
 	flag.Parse()
+	if _, ok := qualModes[qualMode]; !ok {
+		fmt.Println("Error: Unknown -qual-mode", qualMode)
+		return
+	}
+	if maxsize > dna.MaxLength {
+		fmt.Println("Error: max-size is limited to", dna.MaxLength, "in this build, use -tags kmer63 or kmer127 for longer kmers")
+		return
+	}
+	if canonical && (minAbundance > 1 || prefilter) {
+		// Sector counts of canonical kmers are partial, so abundance can
+		// only be judged once merging has totalled them
+		fmt.Println("Error: -canonical counts are only complete after merging, use merging -min-abundance instead of -min-abundance or -prefilter")
+		return
+	}
 	maxmem = maxmem * 1024 * 1024
-	maxrecords = maxmem / (sorters + 2) / uint(unsafe.Sizeof(dna.Minimer{}))
-	var finput = flag.Arg(0)
-	if finput == "" {
+	// Each sorter needs a scratch copy of its sector for the radix sort
+	maxrecords = maxmem / (2*sorters + 2) / uint(unsafe.Sizeof(dna.Minimer{}))
+	if flag.NArg() == 0 {
 		fmt.Println("Error: Must define an input file!")
 		return
 	}
+	if mate2 != "" && interleaved {
+		fmt.Println("Error: -r2 and -interleaved cannot be combined")
+		return
+	}
+	names, err := expandInputs(flag.Args())
+	check(err)
+	if len(names) == 0 {
+		fmt.Println("Error: No sequence files found in", strings.Join(flag.Args(), " "))
+		return
+	}
+	var names2 []string
+	if mate2 != "" {
+		names2, err = expandInputs([]string{mate2})
+		check(err)
+		if len(names2) != len(names) {
+			fmt.Println("Error: Found", len(names), "read 1 files but", len(names2), "read 2 files")
+			return
+		}
+	}
+	finput := names[0]
 	start := time.Now()
 	if foutput == "" {
 		parts := strings.Split(finput, ".")
@@ -195,27 +317,64 @@ func main() {
 		}
 		foutput = strings.Join(parts, ".") + ".partials.h5"
 	}
-	f, err := os.Open(finput)
-	check(err)
-	sectors, sectorbits := calcSectors(f)
+	var inputs []input
+	for i, name := range names {
+		f, err := os.Open(name)
+		check(err)
+		switch {
+		case interleaved:
+			inputs = append(inputs, input{f: f, mate: interleavedMates})
+		case mate2 != "":
+			f2, err := os.Open(names2[i])
+			check(err)
+			inputs = append(inputs, input{f: f, mate: 1}, input{f: f2, mate: 2})
+		default:
+			inputs = append(inputs, input{f: f})
+		}
+	}
+	groupNames := mateGroups(mate2 != "" || interleaved)
+	sectors := calcSectors(inputs, len(groupNames))
+	perGroup := len(sectors) / len(groupNames)
 	fmt.Printf("Created %d sectors\n", len(sectors))
 	var pjoin sync.WaitGroup
 	pjoin.Add(len(sectors))
 	for i := range sectors {
 		go writeChunk(sectors[i], &pjoin)
 	}
-	scan(f, func(kmer dna.Kmer) bool {
-		hash := kmer.ToRaw()[len(kmer.Kmer)-1]
-		for i := 0; i < minsize*2-sectorbits; i++ {
-			hash ^= hash >> 1
+	k := partitionK
+	if k > minsize {
+		k = minsize
+	}
+	partitioner := dna.NewPartitioner(minsize, k, perGroup, dna.Hashed)
+	var sketch *dna.CountMinSketch
+	if prefilter && minAbundance > 1 {
+		sketch = buildSketch(inputs)
+	}
+	_, checksum := scan(inputs, func(mate int, kmer dna.Kmer) bool {
+		if sketch != nil {
+			var ok bool
+			if kmer, ok = solid(kmer, sketch); !ok {
+				return true
+			}
+		}
+		group := 0
+		if mate > 0 {
+			group = mate - 1
 		}
-		sectors[hash&uint64(len(sectors)-1)].c <- kmer
+		sectors[group*perGroup+partitioner.Sector(kmer)].c <- kmer
 		return true
 	}, minsize, maxsize, true)
 	for _, s := range sectors {
 		close(s.c)
 	}
+	check(checkPairs(inputs))
 	pjoin.Wait()
+	for _, s := range sectors {
+		h := sectorHeader()
+		h.Records = uint64(s.len)
+		h.Checksum = checksum
+		check(h.Rewrite(s.tmpfile))
+	}
 	toSort := make(chan *sector)
 	var ojoin sync.WaitGroup
 	var sjoin sync.WaitGroup
@@ -224,19 +383,37 @@ func main() {
 		go processChunks(toSort, &ojoin)
 	}
 	sjoin.Add(1)
-	go saveChunks(foutput, counts, sectors, &sjoin)
+	go saveChunks(foutput, counts, sectors, groupNames, inputs, &sjoin)
 	for _, sector := range sectors {
 		println("Reading sector")
 		data := make(dna.Mmerlist, 0, sector.len*(maxsize-minsize+1))
 		println(cap(data))
 		sector.tmpfile.Seek(0, 0)
-		reader := bufio.NewReaderSize(sector.tmpfile, 4*1024*1024)
-		for j := 0; j < sector.len; j++ {
-			k := dna.ReadKmer(reader)
-			for k.Length >= uint32(minsize) {
-				data = append(data, k.ToMini())
-				k.Cut()
+		dec := dna.NewDecoder(bufio.NewReaderSize(sector.tmpfile, 4*1024*1024))
+		h, err := dec.ReadHeader()
+		check(err)
+		if h.Records != uint64(sector.len) || h.Checksum != checksum {
+			check(fmt.Errorf("sector file %s does not belong to this run", sector.tmpfile.Name()))
+		}
+		batch := make(dna.Mmerlist, 4096)
+		read := 0
+		for {
+			n, err := dec.DecodeInto(batch)
+			for _, mmer := range batch[:n] {
+				k := mmer.ToKmer()
+				for k.Length >= uint32(minsize) {
+					data = append(data, counted(k))
+					k.Cut()
+				}
 			}
+			read += n
+			if err == io.EOF {
+				break
+			}
+			check(err)
+		}
+		if read != sector.len {
+			check(fmt.Errorf("sector file %s holds %d kmers, expected %d", sector.tmpfile.Name(), read, sector.len))
 		}
 		println(cap(data), len(data))
 		sector.tmpfile.Close()
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sbinet/go-hdf5"
+)
+
+// seqExts are the extensions of the sequence files read from directories,
+// optionally followed by a compression extension
+var seqExts = []string{".fa", ".fasta", ".fna", ".fq", ".fastq"}
+
+var compressionExts = []string{".gz", ".bz2", ".zst"}
+
+// isSequence reports whether a file name looks like a sequence file
+func isSequence(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range compressionExts {
+		name = strings.TrimSuffix(name, ext)
+	}
+	for _, ext := range seqExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandInputs turns the arguments into the names of the files to count.
+// Patterns give the files they match and directories the sequence files
+// they hold, in name order.
+func expandInputs(args []string) ([]string, error) {
+	var names []string
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s matches no files", arg)
+			}
+		}
+		for _, name := range matches {
+			fi, err := os.Stat(name)
+			if err != nil {
+				return nil, err
+			}
+			if !fi.IsDir() {
+				names = append(names, name)
+				continue
+			}
+			entries, err := os.ReadDir(name)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !e.IsDir() && isSequence(e.Name()) {
+					names = append(names, filepath.Join(name, e.Name()))
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// checkPairs verifies that the inputs of paired reads, once scanned, hold
+// as many read 1 as read 2 records
+func checkPairs(inputs []input) error {
+	for i, in := range inputs {
+		switch {
+		case in.mate == interleavedMates && in.records%2 != 0:
+			return fmt.Errorf("%s holds %d records, an interleaved file needs an even number", in.f.Name(), in.records)
+		case in.mate == 1 && inputs[i+1].records != in.records:
+			return fmt.Errorf("%s holds %d records but its read 2 file %s holds %d", in.f.Name(), in.records, inputs[i+1].f.Name(), inputs[i+1].records)
+		}
+	}
+	return nil
+}
+
+// inputRecord is a row of the inputs table of an output, naming a counted
+// file, its mate (0 for single end reads, -1 for interleaved pairs) and the
+// number of reads it held. Names are truncated to fit.
+type inputRecord struct {
+	Name    [256]byte `name`
+	Mate    int32     `mate`
+	Records uint64    `records`
+}
+
+// saveInputs writes the inputs table
+func saveInputs(h5 *hdf5.File, inputs []input) {
+	table, err := h5.CreateTableFrom("inputs", inputRecord{}, 64, -1)
+	check(err)
+	rows := make([]inputRecord, len(inputs))
+	for i, in := range inputs {
+		copy(rows[i].Name[:], in.f.Name())
+		rows[i].Mate = int32(in.mate)
+		rows[i].Records = uint64(in.records)
+	}
+	check(table.Append(&rows))
+	check(table.Close())
+}
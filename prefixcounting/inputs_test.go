@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPairs(t *testing.T) {
+	dir := t.TempDir()
+	open := func(name string) *os.File {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+	r1, r2, il := open("r1.fq"), open("r2.fq"), open("il.fq")
+	tests := []struct {
+		name   string
+		inputs []input
+		ok     bool
+	}{
+		{"matched", []input{{f: r1, mate: 1, records: 10}, {f: r2, mate: 2, records: 10}}, true},
+		{"mismatched", []input{{f: r1, mate: 1, records: 10}, {f: r2, mate: 2, records: 9}}, false},
+		{"interleaved", []input{{f: il, mate: interleavedMates, records: 10}}, true},
+		{"odd interleaved", []input{{f: il, mate: interleavedMates, records: 9}}, false},
+		{"single end", []input{{f: il, records: 9}}, true},
+	}
+	for _, tt := range tests {
+		if err := checkPairs(tt.inputs); (err == nil) != tt.ok {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}
@@ -2,8 +2,9 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"fmt"
+	"hash/crc64"
+	"io"
 	"os"
 	"sync"
 	"time"
@@ -11,77 +12,104 @@ import (
 	"github.com/ericpauley/dna"
 )
 
-func parser(ch chan []byte, join *sync.WaitGroup, tocall dna.Kmerhandler, running *bool, min int, max int) {
+// interleavedMates marks an input whose records alternate between mate 1
+// and mate 2
+const interleavedMates = -1
+
+// input is a sequence file and the mate its reads belong to: 0 for single
+// end reads, 1 or 2 for a file of a pair, or interleavedMates. records is
+// the number of reads found by the last scan.
+type input struct {
+	f       *os.File
+	mate    int
+	records int
+}
+
+// mateHandler is called with every kmer and the mate it was read from
+type mateHandler func(mate int, kmer dna.Kmer) bool
+
+// record is a read, its quality string (nil for FASTA) and its mate
+type record struct {
+	seq, qual []byte
+	mate      int
+}
+
+func parser(ch chan record, join *sync.WaitGroup, tocall mateHandler, running *bool, min int, max int) {
 	defer join.Done()
-	for s := range ch {
-		var kmer dna.Kmer
-		var pushed int
-		for i := len(s) - 1; i >= 0; i-- {
-			if s[i]>>3&1 == 0 {
-				bp := uint64(s[i] >> 1 & 3)
-				kmer.Push(bp)
-				pushed++
-				if pushed == max {
-					if !tocall(kmer) {
-						*running = false
-					}
-				}
-			} else {
-				pushed = 0
-				kmer = dna.Kmer{}
-			}
-		}
-		if pushed < max && pushed >= min {
-			if !tocall(kmer) {
-				*running = false
-			}
+	opts := dna.ScanOptions{
+		Mode:       dna.ReadEnds,
+		Min:        min,
+		Max:        max,
+		MinQual:    minQual,
+		Qual:       qualModes[qualMode],
+		QualOffset: qualOffset,
+	}
+	for r := range ch {
+		mate := r.mate
+		if !opts.ScanQual(r.seq, r.qual, func(kmer dna.Kmer) bool { return tocall(mate, kmer) }) {
+			*running = false
 		}
 	}
 }
 
-func scan(f *os.File, tocall dna.Kmerhandler, min int, max int, verbose bool) float64 {
-	f.Seek(0, 0)
+// scan feeds the kmers of the inputs, FASTA or FASTQ files that may be
+// compressed, to tocall and returns the fraction of the inputs read along
+// with the CRC-64 of the part read. Progress is measured in bytes of the
+// files, compressed or not.
+func scan(inputs []input, tocall mateHandler, min int, max int, verbose bool) (float64, uint64) {
 	start := time.Now()
-	fi, err := f.Stat()
-	check(err)
-	size := fi.Size()
-	scanner := bufio.NewScanner(bufio.NewReader(f))
+	var size int64
+	for _, in := range inputs {
+		fi, err := in.f.Stat()
+		check(err)
+		size += fi.Size()
+	}
 	running := true
 
-	c := make(chan []byte)
+	c := make(chan record)
 	var pjoin sync.WaitGroup
 	pjoin.Add(1)
 	for i := 0; i < 1; i++ {
 		go parser(c, &pjoin, tocall, &running, min, max)
 	}
 	index := 0
-	var current []byte
-	line := 0
-	for scanner.Scan() {
-		line++
-		b := scanner.Bytes()
-		if bytes.IndexByte(b, '>') != -1 {
-			index++
-			if index%10000 == 0 {
-				pos, err := f.Seek(0, 1)
-				check(err)
-				if verbose {
-					fmt.Print("\r", scanner.Text(), len(current), pos*100/size, time.Since(start), time.Since(start).Nanoseconds()/1000000*size/pos, "          ")
-				}
-			}
-			c <- current
-			current = make([]byte, 0)
-		} else {
-			current = append(current, b...)
-		}
+	crc := crc64.New(crc64.MakeTable(crc64.ECMA))
+	// done is the size of the inputs read before the current one
+	var done int64
+	for i := range inputs {
+		in := &inputs[i]
 		if !running {
 			break
 		}
+		_, err := in.f.Seek(0, 0)
+		check(err)
+		counter := &dna.CountingReader{R: io.TeeReader(in.f, crc)}
+		r, err := dna.Decompress(counter)
+		check(err)
+		n := 0
+		err = dna.ReadRecords(bufio.NewReader(r), func(name, seq, qual []byte) bool {
+			index++
+			if index%10000 == 0 && verbose && size > 0 {
+				pos := done + counter.N
+				fmt.Print("\r", string(name), len(seq), pos*100/size, time.Since(start), time.Since(start).Nanoseconds()/1000000*size/pos, "          ")
+			}
+			mate := in.mate
+			if mate == interleavedMates {
+				mate = n%2 + 1
+			}
+			n++
+			c <- record{seq, qual, mate}
+			return running
+		})
+		check(err)
+		check(r.Close())
+		in.records = n
+		done += counter.N
 	}
-	c <- current
 	close(c)
 	pjoin.Wait()
-	pos, err := f.Seek(0, 1)
-	check(err)
-	return float64(pos) / float64(size)
+	if size == 0 {
+		return 1, crc.Sum64()
+	}
+	return float64(done) / float64(size), crc.Sum64()
 }
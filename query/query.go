@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+)
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+type result struct {
+	kmer  dna.Kmer
+	count uint32
+}
+
+// summarize prints the min, median, mean and present fraction of counts
+func summarize(out *bufio.Writer, name []byte, length int, results []result) {
+	if len(results) == 0 {
+		fmt.Fprintf(out, "summary\t%s\t%d\t0\t0\t0\t0\t0\n", name, length)
+		return
+	}
+	counts := make([]uint32, len(results))
+	var sum uint64
+	present := 0
+	for i, r := range results {
+		counts[i] = r.count
+		sum += uint64(r.count)
+		if r.count > 0 {
+			present++
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i] < counts[j] })
+	median := float64(counts[len(counts)/2])
+	if len(counts)%2 == 0 {
+		median = (float64(counts[len(counts)/2-1]) + median) / 2
+	}
+	fmt.Fprintf(out, "summary\t%s\t%d\t%d\t%d\t%g\t%g\t%g\n", name, length, len(counts), counts[0], median,
+		float64(sum)/float64(len(counts)), float64(present)/float64(len(counts)))
+}
+
+func main() {
+	var finput string
+	var canonical, summaryOnly bool
+	flag.StringVar(&finput, "fasta", "", "The FASTA file of query sequences")
+	flag.BoolVar(&canonical, "canonical", false, "Look up canonical kmers (tables from -canonical runs)")
+	flag.BoolVar(&summaryOnly, "summary", false, "Only print the per sequence summaries")
+	flag.Parse()
+	names := flag.Args()
+	if finput == "" || len(names) == 0 {
+		fmt.Println("Error: Must define a -fasta query file and merged tables!")
+		return
+	}
+	var tables []*counttable.CountTable
+	for _, name := range names {
+		table, err := counttable.Open(name)
+		check(err)
+		defer table.Close()
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Length() < tables[j].Length() })
+	f, err := os.Open(finput)
+	check(err)
+	defer f.Close()
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	fmt.Fprintln(out, "#kmer\tname\tlength\tsequence\tcount")
+	fmt.Fprintln(out, "#summary\tname\tlength\tkmers\tmin\tmedian\tmean\tpresent")
+	in, err := dna.Decompress(f)
+	check(err)
+	defer in.Close()
+	check(dna.ReadFasta(bufio.NewReader(in), func(name, seq []byte) bool {
+		for _, table := range tables {
+			var results []result
+			opts := dna.ScanOptions{Mode: dna.AllPositions, Min: table.Length(), Max: table.Length()}
+			opts.Scan(seq, func(kmer dna.Kmer) bool {
+				key := kmer
+				if canonical {
+					key = kmer.Canonical()
+				}
+				count, err := table.Count(key)
+				check(err)
+				results = append(results, result{kmer, count})
+				return true
+			})
+			// Scans walk the sequence from its end
+			for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+				results[i], results[j] = results[j], results[i]
+			}
+			if !summaryOnly {
+				for _, r := range results {
+					fmt.Fprintf(out, "kmer\t%s\t%d\t%s\t%d\n", name, table.Length(), r.kmer, r.count)
+				}
+			}
+			summarize(out, name, table.Length(), results)
+		}
+		return true
+	}))
+}
@@ -0,0 +1,173 @@
+package dna
+
+// ScanMode selects the kmers a ScanOptions emits from a sequence
+type ScanMode int
+
+const (
+	// ReadEnds emits the kmer of up to Max base pairs that ends each run of
+	// base pairs. Shorter lengths are left to the caller to Cut from it.
+	ReadEnds ScanMode = iota
+	// AllPositions emits the kmer of up to Max base pairs starting at every
+	// position. Shorter lengths are left to the caller to Truncate from it.
+	AllPositions
+)
+
+// NPolicy selects how a scan treats bytes other than ACGT
+type NPolicy int
+
+const (
+	// ResetAtN splits the sequence at each non-ACGT byte and scans every run
+	// on its own
+	ResetAtN NPolicy = iota
+	// StopAtN ignores the sequence from the first non-ACGT byte on
+	StopAtN
+	// DropAtN skips sequences holding any non-ACGT byte
+	DropAtN
+)
+
+// QualPolicy selects how a scan treats base pairs below MinQual
+type QualPolicy int
+
+const (
+	// TruncateAtQual ignores the sequence from the first low quality base
+	// pair on, counted from the end kmers are anchored at: the read end for
+	// ReadEnds and the read start for AllPositions
+	TruncateAtQual QualPolicy = iota
+	// SkipAtQual skips sequences holding any low quality base pair
+	SkipAtQual
+	// KmerQual emits only kmers whose every base pair reaches MinQual,
+	// shortening them where they would include a low quality one
+	KmerQual
+)
+
+// ScanOptions describes how kmers are taken from a sequence
+type ScanOptions struct {
+	Mode ScanMode
+	// Min and Max bound the length of emitted kmers
+	Min, Max int
+	N        NPolicy
+	// MaskLowercase treats lower case (soft masked) base pairs like N
+	MaskLowercase bool
+	// MinQual is the Phred score base pairs must reach when qualities are
+	// given, or 0 to ignore qualities
+	MinQual int
+	Qual    QualPolicy
+	// QualOffset is the ASCII offset of quality strings, 33 if zero
+	QualOffset int
+}
+
+func (o ScanOptions) encode(b byte) (uint64, bool) {
+	if o.MaskLowercase && b >= 'a' {
+		return 0, false
+	}
+	return EncodeBase(b)
+}
+
+// low reports whether a quality byte falls below MinQual
+func (o ScanOptions) low(q byte) bool {
+	offset := o.QualOffset
+	if offset == 0 {
+		offset = 33
+	}
+	return int(q)-offset < o.MinQual
+}
+
+// Scan calls tocall with the kmers of seq selected by the options, walking
+// the sequence from its end. It returns false if tocall stopped the scan.
+func (o ScanOptions) Scan(seq []byte, tocall Kmerhandler) bool {
+	return o.ScanQual(seq, nil, tocall)
+}
+
+// ScanQual is Scan for a sequence with a quality string of the same length,
+// which is checked against MinQual. A nil qual is not checked.
+func (o ScanOptions) ScanQual(seq, qual []byte, tocall Kmerhandler) bool {
+	if o.MinQual <= 0 || len(qual) != len(seq) {
+		qual = nil
+	}
+	if qual != nil {
+		switch o.Qual {
+		case TruncateAtQual:
+			if o.Mode == ReadEnds {
+				for i := len(qual) - 1; i >= 0; i-- {
+					if o.low(qual[i]) {
+						seq = seq[i+1:]
+						break
+					}
+				}
+				break
+			}
+			for i, q := range qual {
+				if o.low(q) {
+					seq = seq[:i]
+					break
+				}
+			}
+		case SkipAtQual:
+			for _, q := range qual {
+				if o.low(q) {
+					return true
+				}
+			}
+		}
+	}
+	kmerQual := qual != nil && o.Qual == KmerQual
+	switch o.N {
+	case StopAtN:
+		for i, b := range seq {
+			if _, ok := o.encode(b); !ok {
+				seq = seq[:i]
+				break
+			}
+		}
+	case DropAtN:
+		for _, b := range seq {
+			if _, ok := o.encode(b); !ok {
+				return true
+			}
+		}
+	}
+	var kmer Kmer
+	var run int
+	// capped stops a read end kmer at a low quality base pair and lowAt is
+	// the nearest low quality base pair ahead of position i
+	capped, lowAt := false, -1
+	for i := len(seq) - 1; i >= -1; i-- {
+		var bp uint64
+		ok := false
+		if i >= 0 {
+			bp, ok = o.encode(seq[i])
+		}
+		if !ok {
+			if o.Mode == ReadEnds && int(kmer.Length) >= o.Min && run > 0 {
+				if !tocall(kmer) {
+					return false
+				}
+			}
+			kmer = Kmer{}
+			run = 0
+			capped, lowAt = false, -1
+			continue
+		}
+		run++
+		if kmerQual && o.low(qual[i]) {
+			capped, lowAt = true, i
+		}
+		switch o.Mode {
+		case ReadEnds:
+			if run <= o.Max && !capped {
+				kmer.Push(bp)
+			}
+		case AllPositions:
+			kmer.Push(bp)
+			kmer.Truncate(uint32(o.Max))
+			emit := kmer
+			if lowAt >= 0 {
+				emit.Truncate(uint32(lowAt - i))
+			}
+			if int(emit.Length) >= o.Min && !tocall(emit) {
+				return false
+			}
+		}
+	}
+	return true
+}
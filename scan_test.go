@@ -0,0 +1,87 @@
+package dna
+
+import (
+	"reflect"
+	"testing"
+)
+
+func scanAll(t *testing.T, o ScanOptions, seq, qual string) []string {
+	t.Helper()
+	var q []byte
+	if qual != "" {
+		q = []byte(qual)
+	}
+	var out []string
+	o.ScanQual([]byte(seq), q, func(kmer Kmer) bool {
+		out = append(out, kmer.String())
+		return true
+	})
+	return out
+}
+
+func TestScanQualReadEndsKeepsAnchor(t *testing.T) {
+	seq, qual := "AAAACGTGCA", "IIII#III#I"
+	o := ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, MinQual: 20}
+	truncated := scanAll(t, o, seq, qual)
+	o.Qual = KmerQual
+	perKmer := scanAll(t, o, seq, qual)
+	// Both modes stop at the low quality base nearest the read end
+	want := []string{"A"}
+	if !reflect.DeepEqual(truncated, want) {
+		t.Errorf("TruncateAtQual gave %v, want %v", truncated, want)
+	}
+	if !reflect.DeepEqual(perKmer, truncated) {
+		t.Errorf("KmerQual gave %v, TruncateAtQual %v", perKmer, truncated)
+	}
+}
+
+func TestScanQual(t *testing.T) {
+	seq, qual := "ACGTACGT", "IIII#III"
+	tests := []struct {
+		name string
+		o    ScanOptions
+		want []string
+	}{
+		{"read ends truncate", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, MinQual: 20}, []string{"CGT"}},
+		{"read ends skip", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, MinQual: 20, Qual: SkipAtQual}, nil},
+		{"read ends kmer", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, MinQual: 20, Qual: KmerQual}, []string{"CGT"}},
+		{"all positions truncate", ScanOptions{Mode: AllPositions, Min: 1, Max: 3, MinQual: 20}, []string{"T", "GT", "CGT", "ACG"}},
+		{"all positions kmer", ScanOptions{Mode: AllPositions, Min: 1, Max: 3, MinQual: 20, Qual: KmerQual}, []string{"T", "GT", "CGT", "T", "GT", "CGT", "ACG"}},
+		{"qualities ignored", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8}, []string{"ACGTACGT"}},
+		{"phred 64", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, MinQual: 20, QualOffset: 64}, nil},
+	}
+	for _, tt := range tests {
+		if got := scanAll(t, tt.o, seq, qual); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		o    ScanOptions
+		want []string
+	}{
+		{"read ends reset at N", "ACGTNACGGT", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8}, []string{"ACGGT", "ACGT"}},
+		{"read ends stop at N", "ACGTNACGGT", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, N: StopAtN}, []string{"ACGT"}},
+		{"read ends drop at N", "ACGTNACGGT", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, N: DropAtN}, nil},
+		{"read ends max", "ACGTNACGGT", ScanOptions{Mode: ReadEnds, Min: 1, Max: 3}, []string{"GGT", "CGT"}},
+		{"read ends min", "ACGTNACGGT", ScanOptions{Mode: ReadEnds, Min: 5, Max: 8}, []string{"ACGGT"}},
+		{"lowercase kept", "ACgtACGT", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8}, []string{"ACGTACGT"}},
+		{"lowercase masked", "ACgtACGT", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, MaskLowercase: true}, []string{"ACGT", "AC"}},
+		{"lowercase dropped", "ACgtACGT", ScanOptions{Mode: ReadEnds, Min: 1, Max: 8, MaskLowercase: true, N: DropAtN}, nil},
+		{"all positions reset at N", "ACNGT", ScanOptions{Mode: AllPositions, Min: 1, Max: 2}, []string{"T", "GT", "C", "AC"}},
+		{"all positions stop at N", "ACNGT", ScanOptions{Mode: AllPositions, Min: 1, Max: 2, N: StopAtN}, []string{"C", "AC"}},
+		{"all positions drop at N", "ACNGT", ScanOptions{Mode: AllPositions, Min: 1, Max: 2, N: DropAtN}, nil},
+		{"all positions min", "ACNGT", ScanOptions{Mode: AllPositions, Min: 2, Max: 2}, []string{"GT", "AC"}},
+		{"all positions max", "ACGTA", ScanOptions{Mode: AllPositions, Min: 1, Max: 3}, []string{"A", "TA", "GTA", "CGT", "ACG"}},
+		{"all positions masked", "ACgTA", ScanOptions{Mode: AllPositions, Min: 2, Max: 3, MaskLowercase: true}, []string{"TA", "AC"}},
+	}
+	for _, tt := range tests {
+		if got := scanAll(t, tt.o, tt.seq, ""); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package setops combines sorted streams of kmer counts, such as merged
+// tables, into unions, intersections and differences.
+package setops
+
+import "github.com/ericpauley/dna"
+
+const batchSize = 10000
+
+// Operand is a stream of kmer batches in increasing order. Kmers counted
+// below MinAbundance are treated as absent from the operand.
+type Operand struct {
+	Stream       chan []dna.Kmer
+	MinAbundance uint32
+}
+
+type head struct {
+	Operand
+	batch []dna.Kmer
+	done  bool
+}
+
+// peek returns the next kmer of the operand, fetching a batch if needed
+func (h *head) peek() (dna.Kmer, bool) {
+	for !h.done && len(h.batch) == 0 {
+		batch, ok := <-h.Stream
+		h.batch, h.done = batch, !ok
+	}
+	if h.done {
+		return dna.Kmer{}, false
+	}
+	return h.batch[0], true
+}
+
+// Join walks the operands in step and calls tocall with every kmer present
+// in at least one of them along with its count in each operand, zero where
+// absent. Repeated kmers within an operand are summed. The counts slice is
+// reused between calls. Join stops early when tocall returns false.
+func Join(ops []Operand, tocall func(kmer dna.Kmer, counts []uint32) bool) {
+	heads := make([]head, len(ops))
+	for i := range ops {
+		heads[i].Operand = ops[i]
+	}
+	counts := make([]uint32, len(ops))
+	for {
+		var min dna.Kmer
+		found := false
+		for i := range heads {
+			if kmer, ok := heads[i].peek(); ok && (!found || kmer.Cmp(min) < 0) {
+				min, found = kmer, true
+			}
+		}
+		if !found {
+			return
+		}
+		present := false
+		for i := range heads {
+			h := &heads[i]
+			counts[i] = 0
+			for {
+				kmer, ok := h.peek()
+				if !ok || kmer.Cmp(min) != 0 {
+					break
+				}
+				counts[i] += kmer.Count
+				h.batch = h.batch[1:]
+			}
+			if counts[i] < h.MinAbundance {
+				counts[i] = 0
+			}
+			present = present || counts[i] > 0
+		}
+		if present && !tocall(min, counts) {
+			return
+		}
+	}
+}
+
+// Aggregate selects how a union or intersection combines the counts of the
+// operands holding a kmer
+type Aggregate int
+
+const (
+	Sum Aggregate = iota
+	Max
+	Min
+)
+
+func (a Aggregate) combine(counts []uint32) uint32 {
+	var out uint32
+	first := true
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		switch {
+		case a == Sum:
+			out += c
+		case first, a == Max && c > out, a == Min && c < out:
+			out = c
+		}
+		first = false
+	}
+	return out
+}
+
+// collect streams the kmers for which keep returns a count
+func collect(ops []Operand, keep func(counts []uint32) (uint32, bool)) chan []dna.Kmer {
+	c := make(chan []dna.Kmer, 1)
+	go func() {
+		buf := make([]dna.Kmer, 0, batchSize)
+		Join(ops, func(kmer dna.Kmer, counts []uint32) bool {
+			if count, ok := keep(counts); ok {
+				kmer.Count = count
+				buf = append(buf, kmer)
+				if len(buf) >= batchSize {
+					c <- buf
+					buf = make([]dna.Kmer, 0, batchSize)
+				}
+			}
+			return true
+		})
+		if len(buf) > 0 {
+			c <- buf
+		}
+		close(c)
+	}()
+	return c
+}
+
+// Union streams every kmer present in any operand
+func Union(ops []Operand, agg Aggregate) chan []dna.Kmer {
+	return collect(ops, func(counts []uint32) (uint32, bool) {
+		return agg.combine(counts), true
+	})
+}
+
+// Intersect streams the kmers present in every operand
+func Intersect(ops []Operand, agg Aggregate) chan []dna.Kmer {
+	return collect(ops, func(counts []uint32) (uint32, bool) {
+		for _, c := range counts {
+			if c == 0 {
+				return 0, false
+			}
+		}
+		return agg.combine(counts), true
+	})
+}
+
+// Subtract streams the kmers of a that are absent from all others, keeping
+// their count in a
+func Subtract(a Operand, others ...Operand) chan []dna.Kmer {
+	return collect(append([]Operand{a}, others...), func(counts []uint32) (uint32, bool) {
+		for _, c := range counts[1:] {
+			if c > 0 {
+				return 0, false
+			}
+		}
+		return counts[0], counts[0] > 0
+	})
+}
@@ -0,0 +1,109 @@
+package setops
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ericpauley/dna"
+)
+
+// operand streams "KMER:count" kmers, given in increasing order, one per
+// batch after an empty one
+func operand(t *testing.T, min uint32, kmers ...string) Operand {
+	t.Helper()
+	c := make(chan []dna.Kmer, len(kmers)+1)
+	c <- nil
+	for _, s := range kmers {
+		seq, count, _ := strings.Cut(s, ":")
+		kmer, err := dna.ParseKmer(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.ParseUint(count, 10, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kmer.Count = uint32(n)
+		c <- []dna.Kmer{kmer}
+	}
+	close(c)
+	return Operand{Stream: c, MinAbundance: min}
+}
+
+func drain(c chan []dna.Kmer) []string {
+	var out []string
+	for batch := range c {
+		for _, kmer := range batch {
+			out = append(out, fmt.Sprintf("%v:%d", kmer, kmer.Count))
+		}
+	}
+	return out
+}
+
+// ops returns fresh operands for every test, since streams are consumed.
+// A<C<T<G in the 2-bit encoding.
+func ops(t *testing.T) []Operand {
+	return []Operand{
+		operand(t, 0, "AAAA:1", "CCCC:2", "TTTT:5", "GGGG:1"),
+		operand(t, 0, "CCCC:3", "CCCC:1", "GGGG:7"),
+		operand(t, 2, "AAAA:1", "CCCC:2", "GGGG:2"),
+	}
+}
+
+func TestJoin(t *testing.T) {
+	var got []string
+	Join(ops(t), func(kmer dna.Kmer, counts []uint32) bool {
+		got = append(got, fmt.Sprint(kmer, counts))
+		return true
+	})
+	// AAAA of the third operand is below its MinAbundance and repeated
+	// kmers within an operand are summed
+	want := []string{"AAAA [1 0 0]", "CCCC [2 4 2]", "TTTT [5 0 0]", "GGGG [1 7 2]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	calls := 0
+	Join(ops(t), func(dna.Kmer, []uint32) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Join made %d calls after tocall returned false", calls)
+	}
+}
+
+func TestJoinSkipsKmersBelowEveryThreshold(t *testing.T) {
+	var got []string
+	Join([]Operand{operand(t, 3, "AAAA:2", "CCCC:3"), operand(t, 5, "AAAA:4")}, func(kmer dna.Kmer, counts []uint32) bool {
+		got = append(got, fmt.Sprint(kmer, counts))
+		return true
+	})
+	if want := []string{"CCCC [3 0]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func([]Operand) chan []dna.Kmer
+		want []string
+	}{
+		{"union sum", func(o []Operand) chan []dna.Kmer { return Union(o, Sum) }, []string{"AAAA:1", "CCCC:8", "TTTT:5", "GGGG:10"}},
+		{"union max", func(o []Operand) chan []dna.Kmer { return Union(o, Max) }, []string{"AAAA:1", "CCCC:4", "TTTT:5", "GGGG:7"}},
+		{"union min", func(o []Operand) chan []dna.Kmer { return Union(o, Min) }, []string{"AAAA:1", "CCCC:2", "TTTT:5", "GGGG:1"}},
+		{"intersect sum", func(o []Operand) chan []dna.Kmer { return Intersect(o, Sum) }, []string{"CCCC:8", "GGGG:10"}},
+		{"intersect min", func(o []Operand) chan []dna.Kmer { return Intersect(o, Min) }, []string{"CCCC:2", "GGGG:1"}},
+		{"subtract", func(o []Operand) chan []dna.Kmer { return Subtract(o[0], o[1:]...) }, []string{"AAAA:1", "TTTT:5"}},
+		{"subtract nothing", func(o []Operand) chan []dna.Kmer { return Subtract(o[1]) }, []string{"CCCC:4", "GGGG:7"}},
+	}
+	for _, tt := range tests {
+		if got := drain(tt.run(ops(t))); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ericpauley/dna"
+	"github.com/ericpauley/dna/counttable"
+)
+
+// sketchExt marks saved sketches, which are loaded instead of rebuilt
+const sketchExt = ".sketch"
+
+var k = 21
+var size = 1000
+var canonical = true
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+// load builds the sketch of a FASTA file or merged table, or reads a saved one
+func load(name string) *dna.MinHash {
+	if strings.HasSuffix(name, sketchExt) {
+		f, err := os.Open(name)
+		check(err)
+		defer f.Close()
+		m, err := dna.ReadMinHash(bufio.NewReader(f))
+		check(err)
+		return m
+	}
+	if length, err := counttable.LengthFromName(name); err == nil {
+		m := dna.NewMinHash(name, length, size, canonical)
+		table, err := counttable.Open(name)
+		check(err)
+		defer table.Close()
+		for kmers := range table.Stream() {
+			for _, kmer := range kmers {
+				m.Add(kmer)
+			}
+		}
+		check(table.Err())
+		return m
+	}
+	m := dna.NewMinHash(name, k, size, canonical)
+	f, err := os.Open(name)
+	check(err)
+	defer f.Close()
+	in, err := dna.Decompress(f)
+	check(err)
+	defer in.Close()
+	check(dna.ReadFasta(bufio.NewReader(in), func(_, seq []byte) bool {
+		m.AddSequence(seq)
+		return true
+	}))
+	return m
+}
+
+func printMatrix(title string, sketches []*dna.MinHash, measure func(a, b *dna.MinHash) (float64, error)) {
+	fmt.Println("#" + title)
+	fmt.Print("query")
+	for _, s := range sketches {
+		fmt.Print("\t", s.Name)
+	}
+	fmt.Println()
+	for _, a := range sketches {
+		fmt.Print(a.Name)
+		for _, b := range sketches {
+			v, err := measure(a, b)
+			check(err)
+			fmt.Printf("\t%.6f", v)
+		}
+		fmt.Println()
+	}
+}
+
+func main() {
+	var save bool
+	flag.IntVar(&k, "k", 21, "Kmer size for FASTA inputs, merged tables use their own")
+	flag.IntVar(&size, "size", 1000, "Number of hashes kept per sketch")
+	flag.BoolVar(&canonical, "canonical", true, "Sketch canonical kmers")
+	flag.BoolVar(&save, "save", false, "Save each built sketch as <input>"+sketchExt)
+	flag.Parse()
+	names := flag.Args()
+	if len(names) == 0 {
+		fmt.Println("Error: Must define an input file!")
+		return
+	}
+	if k < 1 || k > dna.MaxLength {
+		fmt.Println("Error: k must be between 1 and", dna.MaxLength)
+		return
+	}
+	var sketches []*dna.MinHash
+	for _, name := range names {
+		m := load(name)
+		if save && !strings.HasSuffix(name, sketchExt) {
+			f, err := os.Create(name + sketchExt)
+			check(err)
+			_, err = m.WriteTo(f)
+			check(err)
+			check(f.Close())
+		}
+		sketches = append(sketches, m)
+	}
+	printMatrix("jaccard", sketches, (*dna.MinHash).Jaccard)
+	printMatrix("containment", sketches, (*dna.MinHash).Containment)
+	printMatrix("mash-distance", sketches, (*dna.MinHash).MashDistance)
+}
@@ -0,0 +1,96 @@
+package dna
+
+import "sync"
+
+// RadixSort sorts the list with a least significant digit radix sort spread
+// over up to workers goroutines. It allocates a scratch copy of the list and
+// skips any byte that is the same in every Minimer, which is common for the
+// zero padded tails of short kmers.
+func (a Mmerlist) RadixSort(workers int) {
+	n := len(a)
+	if n < 2 {
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+	chunk := (n + workers - 1) / workers
+	bounds := func(w int) (int, int) {
+		lo, hi := w*chunk, (w+1)*chunk
+		if lo > n {
+			lo = n
+		}
+		if hi > n {
+			hi = n
+		}
+		return lo, hi
+	}
+	counts := make([][256]int, workers)
+	src, dst := a, make(Mmerlist, n)
+	var wg sync.WaitGroup
+	for word := int(kmerwords) - 1; word >= 0; word-- {
+		for shift := uint(0); shift < 64; shift += 8 {
+			wg.Add(workers)
+			for w := 0; w < workers; w++ {
+				go func(w int) {
+					defer wg.Done()
+					lo, hi := bounds(w)
+					count := &counts[w]
+					*count = [256]int{}
+					for _, mmer := range src[lo:hi] {
+						count[mmer[word]>>shift&0xff]++
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			// Skip digits that would leave the order unchanged
+			var totals [256]int
+			same := false
+			for d := range totals {
+				for w := range counts {
+					totals[d] += counts[w][d]
+				}
+				if totals[d] == n {
+					same = true
+				}
+			}
+			if same {
+				continue
+			}
+
+			// Turn the counts into starting offsets, digit major and worker
+			// minor so that each pass stays stable
+			offset := 0
+			for d := range totals {
+				for w := range counts {
+					count := counts[w][d]
+					counts[w][d] = offset
+					offset += count
+				}
+			}
+
+			wg.Add(workers)
+			for w := 0; w < workers; w++ {
+				go func(w int) {
+					defer wg.Done()
+					lo, hi := bounds(w)
+					offsets := &counts[w]
+					for _, mmer := range src[lo:hi] {
+						d := mmer[word] >> shift & 0xff
+						dst[offsets[d]] = mmer
+						offsets[d]++
+					}
+				}(w)
+			}
+			wg.Wait()
+			src, dst = dst, src
+		}
+	}
+	if &src[0] != &a[0] {
+		copy(a, src)
+	}
+}
@@ -0,0 +1,72 @@
+package dna
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// randomMmers returns n Minimers of random kmers of 1 to MaxLength base
+// pairs, or of random words when raw is set
+func randomMmers(r *rand.Rand, n int, raw bool) Mmerlist {
+	list := make(Mmerlist, n)
+	for i := range list {
+		if raw {
+			for w := range list[i] {
+				list[i][w] = r.Uint64()
+			}
+			continue
+		}
+		var kmer Kmer
+		for l := r.Intn(MaxLength) + 1; l > 0; l-- {
+			kmer.Push(uint64(r.Intn(4)))
+		}
+		list[i] = kmer.ToMini()
+	}
+	return list
+}
+
+func checkRadixSort(t *testing.T, name string, list Mmerlist, workers int) {
+	t.Helper()
+	want := append(Mmerlist(nil), list...)
+	sort.Sort(want)
+	got := append(Mmerlist(nil), list...)
+	got.RadixSort(workers)
+	if len(got) != len(want) || (len(got) > 0 && !reflect.DeepEqual(got, want)) {
+		t.Errorf("%s with %d workers: RadixSort disagrees with sort.Sort", name, workers)
+	}
+}
+
+func TestRadixSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var same Mmerlist
+	for i := 0; i < 1000; i++ {
+		var m Minimer
+		for w := range m {
+			m[w] = 0x5a5a5a5a5a5a5a5a
+		}
+		same = append(same, m)
+	}
+	// Lists that differ only in one byte exercise the skipped digits
+	oneByte := make(Mmerlist, 1000)
+	for i := range oneByte {
+		oneByte[i][len(oneByte[i])-1] = uint64(r.Intn(256)) << 8
+	}
+	lists := map[string]Mmerlist{
+		"empty":     {},
+		"single":    randomMmers(r, 1, false),
+		"pair":      randomMmers(r, 2, false),
+		"kmers":     randomMmers(r, 20000, false),
+		"words":     randomMmers(r, 20000, true),
+		"same":      same,
+		"one byte":  oneByte,
+		"duplicate": append(randomMmers(r, 500, false), randomMmers(rand.New(rand.NewSource(1)), 500, false)...),
+	}
+	for name, list := range lists {
+		// More workers than Minimers leaves some without any
+		for _, workers := range []int{0, 1, 3, 64, 1500} {
+			checkRadixSort(t, name, list, workers)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package dna
+
+import "fmt"
+
+// bases maps the two bit encoding back to base pairs
+const bases = "ACTG"
+
+// EncodeBase returns the two bit encoding of an upper or lower case base pair
+func EncodeBase(b byte) (uint64, bool) {
+	switch b {
+	case 'A', 'a':
+		return 0, true
+	case 'C', 'c':
+		return 1, true
+	case 'T', 't':
+		return 2, true
+	case 'G', 'g':
+		return 3, true
+	}
+	return 0, false
+}
+
+// String decodes the first Length base pairs of the kmer
+func (kmer Kmer) String() string {
+	b := make([]byte, kmer.Length)
+	for i := range b {
+		b[i] = bases[kmer.Kmer[i/32]>>(62-2*(i%32))&3]
+	}
+	return string(b)
+}
+
+// String decodes a sentinel terminated Minimer. Raw LSB aligned values such
+// as those returned by ToRaw do not carry a sentinel and must be normalized
+// into a Kmer first.
+func (kmer Minimer) String() string {
+	if kmer == (Minimer{}) {
+		return ""
+	}
+	return kmer.ToKmer().String()
+}
+
+// ParseKmer encodes a sequence of upper or lower case ACGT base pairs
+func ParseKmer(s string) (Kmer, error) {
+	if len(s) > MaxLength {
+		return Kmer{}, fmt.Errorf("dna: kmer of length %d is longer than %d", len(s), MaxLength)
+	}
+	var kmer Kmer
+	for i := len(s) - 1; i >= 0; i-- {
+		bp, ok := EncodeBase(s[i])
+		if !ok {
+			return Kmer{}, fmt.Errorf("dna: invalid base %q at position %d", s[i], i)
+		}
+		kmer.Push(bp)
+	}
+	kmer.Count = 1
+	return kmer, nil
+}
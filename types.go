@@ -1,14 +1,17 @@
 package dna
 
 import (
-	"encoding/binary"
 	"io"
 	"math/rand"
 )
 
-const kmerwords uint = 1
 const maxCount = 32*kmerwords - 1
 
+// MaxLength is the longest kmer a Minimer can hold alongside its sentinel
+const MaxLength = int(maxCount)
+
+// Minimer is a sentinel terminated, MSB aligned kmer packed two bits per base
+// pair. Its width is selected at build time, see kmerwords.
 type Minimer [kmerwords]uint64
 
 /*Kmer represents a kmer and its count */
@@ -34,6 +37,17 @@ func (kmer *Kmer) Cut() {
 
 // Rsh right shifts the kmer count bits
 func (kmer *Minimer) Rsh(count uint32) {
+	words := int(count / 64)
+	count %= 64
+	if words > 0 {
+		for i := len(kmer) - 1; i >= 0; i-- {
+			if i >= words {
+				kmer[i] = kmer[i-words]
+			} else {
+				kmer[i] = 0
+			}
+		}
+	}
 	var carry uint64
 	for i := range kmer {
 		carry, kmer[i] = kmer[i]<<(64-count), kmer[i]>>count|carry
@@ -42,6 +56,17 @@ func (kmer *Minimer) Rsh(count uint32) {
 
 // Lsh left shifts the kmer count bits
 func (kmer *Minimer) Lsh(count uint32) {
+	words := int(count / 64)
+	count %= 64
+	if words > 0 {
+		for i := range kmer {
+			if i+words < len(kmer) {
+				kmer[i] = kmer[i+words]
+			} else {
+				kmer[i] = 0
+			}
+		}
+	}
 	var carry uint64
 	for i := len(kmer) - 1; i >= 0; i-- {
 		carry, kmer[i] = kmer[i]>>(64-count), kmer[i]<<count|carry
@@ -58,9 +83,9 @@ func (kmer *Kmer) Normalize(length uint32) {
 func (kmer *Kmer) Truncate(length uint32) {
 	if kmer.Length > length {
 		for i := range kmer.Kmer {
-			if uint32(i) > kmer.Length/32 {
+			if uint32(i) > length/32 {
 				kmer.Kmer[i] = 0
-			} else if uint32(i) == kmer.Length/32 {
+			} else if uint32(i) == length/32 {
 				kmer.Kmer[i] = kmer.Kmer[i] & ^(^uint64(0) >> (2 * (length - uint32(32*i))))
 			}
 		}
@@ -95,15 +120,65 @@ func (kmer Minimer) Cmp(rhs Minimer) int {
 	return 0
 }
 
+// ReverseComplement returns the kmer read from the opposite strand. With the
+// A=0, C=1, T=2, G=3 encoding a base is complemented by flipping its high bit.
+func (kmer Kmer) ReverseComplement() Kmer {
+	out := kmer
+	out.Kmer = Minimer{}
+	for i := uint32(0); i < kmer.Length; i++ {
+		j := kmer.Length - 1 - i
+		bp := (kmer.Kmer[i/32] >> (62 - 2*(i%32)) & 3) ^ 2
+		out.Kmer[j/32] |= bp << (62 - 2*(j%32))
+	}
+	return out
+}
+
+// lexicalCmp compares two Minimers base by base in A<C<G<T order, which
+// differs from the A<C<T<G order of the encoding
+func lexicalCmp(a, b Minimer) int {
+	const low = 0x5555555555555555
+	for i := range a {
+		// Swap T and G by setting each base's low bit to the XOR of its bits
+		x, y := a[i]^(a[i]>>1&low), b[i]^(b[i]>>1&low)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Canonical returns the kmer or its reverse complement, whichever comes
+// first in A<C<G<T order
+func (kmer Kmer) Canonical() Kmer {
+	rc := kmer.ReverseComplement()
+	if lexicalCmp(rc.Kmer, kmer.Kmer) < 0 {
+		return rc
+	}
+	return kmer
+}
+
+// ReverseComplement returns the Minimer read from the opposite strand
+func (kmer Minimer) ReverseComplement() Minimer {
+	return kmer.ToKmer().ReverseComplement().ToMini()
+}
+
+// Canonical returns the Minimer or its reverse complement, whichever comes
+// first in A<C<G<T order
+func (kmer Minimer) Canonical() Minimer {
+	return kmer.ToKmer().Canonical().ToMini()
+}
+
 func (kmer Kmer) ToMini() Minimer {
 	mmer := kmer.Kmer
-	mmer[kmer.Length/64] |= (3 << (62 - (kmer.Length%32)*2))
+	mmer[kmer.Length/32] |= (3 << (62 - (kmer.Length%32)*2))
 	return mmer
 }
 
 func (kmer Minimer) ToKmer() Kmer {
 	var out Kmer
-	out.Length = uint32(kmerwords*32 - 1)
+	out.Length = uint32(maxCount)
 	for kmer[kmerwords-1]&3 == 0 {
 		kmer.Rsh(2)
 		out.Length--
@@ -120,25 +195,20 @@ func (kmer *Kmer) GetPrefix() uint {
 	return uint(kmer.Kmer[0] >> (64 - 12))
 }
 
-func (kmer Minimer) Write(w io.Writer) {
-	var b [kmerwords * 8]byte
-	for i := 0; uint(i) < kmerwords; i++ {
-		binary.LittleEndian.PutUint64(b[i*8:(i+1)*8], uint64(kmer[i]))
-	}
-	w.Write(b[:])
+// Write the Minimer to w, see Encoder for writing streams
+func (kmer Minimer) Write(w io.Writer) error {
+	return NewEncoder(w).Encode(kmer)
 }
 
-func (kmer Kmer) Write(w io.Writer) {
-	kmer.ToMini().Write(w)
+// Write the Minimer form of the Kmer to w
+func (kmer Kmer) Write(w io.Writer) error {
+	return kmer.ToMini().Write(w)
 }
 
+// ReadMinimer reads a single Minimer from r. It cannot report errors, use a
+// Decoder when the stream may be truncated.
 func ReadMinimer(r io.Reader) Minimer {
-	var b = make([]byte, kmerwords*8)
-	r.Read(b)
-	var kmer Minimer
-	for i := 0; uint(i) < kmerwords; i++ {
-		kmer[i] = binary.LittleEndian.Uint64(b[i*8 : (i+1)*8])
-	}
+	kmer, _ := NewDecoder(r).Decode()
 	return kmer
 }
 
@@ -0,0 +1,27 @@
+package dna
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"GA", "GA"},
+		{"TC", "GA"},
+		{"ACGT", "ACGT"},
+		{"TTTT", "AAAA"},
+		{"CAT", "ATG"},
+		{"GGGA", "GGGA"},
+		{"TCCC", "GGGA"},
+	}
+	for _, tt := range tests {
+		kmer, err := ParseKmer(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := kmer.Canonical().String(); got != tt.want {
+			t.Errorf("Canonical(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+		if got := kmer.ToMini().Canonical().String(); got != tt.want {
+			t.Errorf("Minimer Canonical(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}